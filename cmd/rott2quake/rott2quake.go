@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"encoding/binary"
 	"flag"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
@@ -14,9 +19,12 @@ import (
 	"gitlab.com/camtap/rott2quake/pkg/imgutil"
 	"gitlab.com/camtap/rott2quake/pkg/lumps"
 	"gitlab.com/camtap/rott2quake/pkg/pak"
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
 	rtlfile "gitlab.com/camtap/rott2quake/pkg/rtl"
+	"gitlab.com/camtap/rott2quake/pkg/udmf"
 	"gitlab.com/camtap/rott2quake/pkg/wad"
 	"gitlab.com/camtap/rott2quake/pkg/wad2"
+	"gitlab.com/camtap/rott2quake/pkg/wal"
 )
 
 func init() {
@@ -30,6 +38,18 @@ func dumpRawLumpDataToFile(destFhnd io.WriteSeeker, lumpReader io.Reader) (int64
 	return io.Copy(destFhnd, lumpReader)
 }
 
+// adds the "+0"/"+a" frames of switch graphics so func_buttons using
+// them flip when pressed
+func addSwitchTextureLumps(wad2Writer *wad2.WADWriter, lumpName string, img *image.RGBA) {
+	for _, switchLumpName := range rtlfile.SwitchTextureLumps(lumpName) {
+		mipdata, err := wad2.RGBAImageToMIPTexture(img, switchLumpName)
+		if err != nil {
+			log.Fatalf("Could not get MIP texture from switch: %v\n", err)
+		}
+		wad2Writer.AddLump(switchLumpName, mipdata, wad2.LT_MIPTEX)
+	}
+}
+
 func dumpLumpDataToFile(archive lumps.ArchiveReader, entry lumps.ArchiveEntry, destFname string,
 	dataType string, wad2Writer *wad2.WADWriter) {
 	lumpReader, err := entry.Open()
@@ -186,6 +206,7 @@ func dumpLumpDataToFile(archive lumps.ArchiveReader, entry lumps.ArchiveEntry, d
 				log.Fatalf("Could not get MIP texture from flat: %v\n", err)
 			}
 			wad2Writer.AddLump("{"+entryName, mipdata, wad2.LT_MIPTEX)
+			addSwitchTextureLumps(wad2Writer, entryName, imgutil.AlignImageDimensions(img, 64))
 		} else if dataType == "tpatch" {
 			rawLumpReader, err := entry.Open()
 			if err != nil {
@@ -200,6 +221,7 @@ func dumpLumpDataToFile(archive lumps.ArchiveReader, entry lumps.ArchiveEntry, d
 				log.Fatalf("Could not get MIP texture from flat: %v\n", err)
 			}
 			wad2Writer.AddLump("{"+entry.Name(), mipdata, wad2.LT_MIPTEX)
+			addSwitchTextureLumps(wad2Writer, entry.Name(), imgutil.AlignImageDimensions(img, 64))
 		} else if dataType == "wall" {
 			if animWall, frameNum := rtlfile.GetAnimatedWallInfo(entry.Name()); animWall != nil {
 				// dump animated wall
@@ -232,11 +254,195 @@ func dumpLumpDataToFile(archive lumps.ArchiveReader, entry lumps.ArchiveEntry, d
 					log.Fatalf("Could not get MIP texture from flat: %v\n", err)
 				}
 				wad2Writer.AddLump(entry.Name(), mipdata, wad2.LT_MIPTEX)
+				addSwitchTextureLumps(wad2Writer, entry.Name(), img)
+
+				if rtlfile.IsWindowLump(entry.Name()) {
+					// see-through version for windows
+					rawLumpReader, err := entry.Open()
+					if err != nil {
+						log.Fatalf("Could not get %s lump data: %v\n", entry.Name(), err)
+					}
+					img, err := wad.GetImageFromFlatData(rawLumpReader, archive, 64, 64, true)
+					if err != nil {
+						log.Fatalf("Could not get flat data image: %v\n", err)
+					}
+					mipdata, err := wad2.RGBAImageToMIPTexture(img, "{"+entry.Name())
+					if err != nil {
+						log.Fatalf("Could not get MIP texture from window: %v\n", err)
+					}
+					wad2Writer.AddLump("{"+entry.Name(), mipdata, wad2.LT_MIPTEX)
+				}
 			}
 		}
 	}
 }
 
+// reads the Quake 2 palette from pics/colormap.pcx, either extracted or
+// inside pak0.pak
+func loadQuake2Palette(palettePath string) (color.Palette, error) {
+	fhnd, err := os.Open(palettePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fhnd.Close()
+
+	var pcxReader io.Reader = fhnd
+	if strings.EqualFold(filepath.Ext(palettePath), ".pak") {
+		pakReader, err := pak.NewPAKReader(fhnd)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := pakReader.GetEntry("pics/colormap.pcx")
+		if err != nil {
+			return nil, err
+		}
+		if pcxReader, err = entry.Open(); err != nil {
+			return nil, err
+		}
+	}
+	pcxData, err := ioutil.ReadAll(pcxReader)
+	if err != nil {
+		return nil, err
+	}
+	return imgutil.PCXPalette(pcxData)
+}
+
+// writes each texture of the wad2 file as a .wal file named after
+// rtl.Quake2TextureName, chaining the "+0", "+1", ... frames of
+// animated textures
+func writeWALTextures(wad2Out *wad2.WADWriter, walOut string, palette color.Palette) error {
+	if err := os.MkdirAll(walOut, 0755); err != nil {
+		return err
+	}
+	translation := wal.PaletteTranslation(imgutil.QuakePalette, palette)
+
+	lumpNames := make(map[string]bool)
+	for _, lump := range wad2Out.Directory {
+		lumpNames[strings.ToLower(lump.Name)] = true
+	}
+
+	count := 0
+	for _, lump := range wad2Out.Directory {
+		if lump.Type != wad2.LT_MIPTEX {
+			continue
+		}
+		name := strings.ToLower(lump.Name)
+		animName := ""
+		var frame int
+		var baseName string
+		if _, err := fmt.Sscanf(name, "+%1d%s", &frame, &baseName); err == nil {
+			animName = rtlfile.Quake2TextureName(fmt.Sprintf("+%d%s", frame+1, baseName))
+			if !lumpNames[fmt.Sprintf("+%d%s", frame+1, baseName)] {
+				animName = rtlfile.Quake2TextureName("+0" + baseName)
+			}
+		}
+		walData, err := wal.MIPTextureToWAL(lump.Data, rtlfile.Quake2TextureName(name), animName, &translation)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(walOut, name+".wal"), walData, 0644); err != nil {
+			return err
+		}
+		count++
+	}
+	fmt.Printf("%d .wal textures written to %s\n", count, walOut)
+	return nil
+}
+
+// returns the image of a wall, sky or picture lump
+func getLumpImage(archive lumps.ArchiveReader, entry lumps.ArchiveEntry) (*image.RGBA, error) {
+	lumpReader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	dataType, _ := entry.GuessFileTypeAndSubdir()
+	switch dataType {
+	case "wall":
+		return wad.GetImageFromFlatData(lumpReader, archive, 64, 64, false)
+	case "sky":
+		return wad.GetImageFromFlatData(lumpReader, archive, 256, 200, false)
+	case "patch":
+		return wad.GetImageFromPatchData(entry, lumpReader, archive)
+	case "tpatch":
+		return wad.GetImageFromTransPatchData(entry, lumpReader, archive)
+	case "lpic":
+		return wad.GetImageFromLpicData(entry, lumpReader, archive)
+	case "pic":
+		return wad.GetImageFromPicData(entry, lumpReader, archive)
+	}
+	return nil, fmt.Errorf("%s is not an image (%s)", entry.Name(), dataType)
+}
+
+// writes the UDMF maps to a PWAD, along with every texture they use as a
+// PNG between TX_START and TX_END
+func writeUDMFPWAD(archive lumps.ArchiveReader, maps []*udmf.Map, mapNames []string, udmfOut string) error {
+	pwad, err := wad.NewPWADWriter()
+	if err != nil {
+		return err
+	}
+	textures := make(map[string]bool)
+	var textureNames []string
+	for i, m := range maps {
+		if err := pwad.AddLump(mapNames[i], nil); err != nil {
+			return err
+		}
+		if err := pwad.AddLump("TEXTMAP", []byte(m.Render())); err != nil {
+			return err
+		}
+		if err := pwad.AddLump("ENDMAP", nil); err != nil {
+			return err
+		}
+		for _, texture := range m.Textures() {
+			texture = strings.ToUpper(texture)
+			if !textures[texture] && texture != rtlfile.UDMFSkyFlat {
+				textures[texture] = true
+				textureNames = append(textureNames, texture)
+			}
+		}
+	}
+
+	if err := pwad.AddLump("TX_START", nil); err != nil {
+		return err
+	}
+	for _, texture := range textureNames {
+		entry, err := archive.GetEntry(texture)
+		if err != nil {
+			log.Printf("Texture %s not found in %s, skipping", texture, archive.Type())
+			continue
+		}
+		img, err := getLumpImage(archive, entry)
+		if err != nil {
+			log.Printf("Could not get image for texture %s: %v", texture, err)
+			continue
+		}
+		var data bytes.Buffer
+		if err := png.Encode(&data, img); err != nil {
+			return err
+		}
+		if err := pwad.AddLump(texture, data.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := pwad.AddLump("TX_END", nil); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(udmfOut), 0755); err != nil {
+		return err
+	}
+	fhnd, err := os.Create(udmfOut)
+	if err != nil {
+		return err
+	}
+	defer fhnd.Close()
+	written, err := pwad.Write(fhnd)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("PWAD file %s written (%d maps, %d bytes)\n", udmfOut, len(maps), written)
+	return nil
+}
+
 type MultiString []string
 
 func (m *MultiString) String() string {
@@ -248,12 +454,37 @@ func (m *MultiString) Set(what string) error {
 	return nil
 }
 
+// parses -keys values, either a strategy for all maps or
+// <map number>:<strategy> for a single map
+func parseKeyStrategies(values []string) (rtlfile.KeyStrategy, map[int]rtlfile.KeyStrategy, error) {
+	defaultStrategy := rtlfile.KEYS_Auto
+	mapStrategies := make(map[int]rtlfile.KeyStrategy)
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 2)
+		strategy, err := rtlfile.ParseKeyStrategy(parts[len(parts)-1])
+		if err != nil {
+			return defaultStrategy, nil, err
+		}
+		if len(parts) == 1 {
+			defaultStrategy = strategy
+			continue
+		}
+		var mapNumber int
+		if _, err := fmt.Sscanf(parts[0], "%d", &mapNumber); err != nil {
+			return defaultStrategy, nil, fmt.Errorf("invalid map number in %q", value)
+		}
+		mapStrategies[mapNumber] = strategy
+	}
+	return defaultStrategy, mapStrategies, nil
+}
+
 func main() {
 	var dumpLumpData, printLumps, dumpRaw bool
 	var rtlFile, rtlMapOutdir, lumpName, lumpType string
 	var wadOut string
 	var isQuakeWad, isPak bool
 	var convertToDusk bool
+	var targetName string
 	var rtl *rtlfile.RTL
 	var rtlMapNumber int
 	var printRTLInfo bool
@@ -261,6 +492,15 @@ func main() {
 	var wadExtractor lumps.ArchiveReader
 	var additionalWads MultiString
 	var fgdFile string
+	var keyStrategies MultiString
+	var mapNames string
+	var bossHealthScale float64
+	var mappingFile string
+	var mapFormatName string
+	var walOut, quake2PalettePath string
+	var udmfOut string
+	var udmfMaps []*udmf.Map
+	var udmfMapNames []string
 
 	flag.StringVar(&rtlFile, "rtl", "", "RTL file")
 	flag.BoolVar(&isPak, "pak", false, "Input file is Quake .pak file")
@@ -268,11 +508,20 @@ func main() {
 	flag.StringVar(&lumpType, "ltype", "", "force specific lump type (only relevant when -lname is specified)")
 	flag.BoolVar(&printRTLInfo, "print-rtl-info", false, "Print RTL metadata (requires -rtl)")
 	flag.StringVar(&wadOut, "wad-out", "", "output ripped image assets to Quake wad2 file (requires -dump)")
+	flag.StringVar(&walOut, "wal-out", "", "also output the ripped textures as Quake 2 .wal files to this folder (requires -wad-out and -q2-palette)")
+	flag.StringVar(&quake2PalettePath, "q2-palette", "", "Quake 2 pics/colormap.pcx, or the pak0.pak containing it, to take the .wal palette from")
 	flag.Var(&additionalWads, "add-wad", "Path to additional WAD file to add to .map files. Can be specified multiple times.")
+	flag.StringVar(&mappingFile, "mapping", "", "Path to a JSON file overriding the item, enemy, door and masked wall tables")
 	flag.StringVar(&fgdFile, "fgd", "", "Path to .fgd file to include in .map files.")
 	flag.BoolVar(&isQuakeWad, "quake", false, "wad specified is from Quake, not ROTT")
-	flag.BoolVar(&convertToDusk, "dusk", false, "generate maps for Dusk rather than Quake (requires -rtl-map-outdir)")
+	flag.StringVar(&targetName, "target", "quake", fmt.Sprintf("game to generate maps for (%s, requires -rtl-map-outdir)", strings.Join(rtlfile.TargetProfileNames(), ", ")))
+	flag.BoolVar(&convertToDusk, "dusk", false, "shorthand for -target dusk")
+	flag.StringVar(&udmfOut, "udmf-out", "", "also convert maps to UDMF and write them with their textures to this PWAD for GZDoom (requires -rtl-map-outdir)")
 	flag.StringVar(&rtlMapOutdir, "rtl-map-outdir", "", "Write RTL ASCII map out to this folder")
+	flag.Var(&keyStrategies, "keys", "How keys are converted (auto, items, triggered), use <map>:<strategy> for a single map. Can be specified multiple times.")
+	flag.StringVar(&mapNames, "map-names", "numbered", "Name converted maps by number (map001) or by episode (e1m1)")
+	flag.Float64Var(&bossHealthScale, "boss-health-scale", 0, "Scale the health of bosses by this factor through their health key (needs a mod or source port supporting it)")
+	flag.StringVar(&mapFormatName, "map-format", "standard", "Texture format of generated .map files (standard, valve220)")
 	flag.Float64Var(&rtlMapScale, "rtl-map-scale", 1.0, "Scale generated maps by this factor")
 	flag.IntVar(&rtlMapNumber, "map", 0, "Dump certain map (defaults to all maps)")
 	flag.BoolVar(&dumpLumpData, "dump", false, "Dump Lump Data out to dest dir")
@@ -285,6 +534,18 @@ func main() {
 		os.Exit(2)
 	}
 
+	if mappingFile != "" {
+		mappingFhnd, err := os.Open(mappingFile)
+		if err != nil {
+			log.Fatalf("Could not open mapping file %s: %v\n", mappingFile, err)
+		}
+		err = rtlfile.LoadMappingConfig(mappingFhnd)
+		mappingFhnd.Close()
+		if err != nil {
+			log.Fatalf("Could not load mapping file %s: %v\n", mappingFile, err)
+		}
+	}
+
 	if rtlFile != "" {
 		rtlFhnd, err := os.Open(rtlFile)
 		if err != nil {
@@ -307,9 +568,24 @@ func main() {
 			log.Fatalf("Must provide RTL file when dumping map data")
 		}
 		if convertToDusk {
-			log.Printf("Converting maps for Dusk")
-		} else {
-			log.Printf("Converting maps for Quake")
+			targetName = "dusk"
+		}
+		target, err := rtlfile.GetTargetProfile(targetName)
+		if err != nil {
+			log.Fatalf("Could not parse -target: %v\n", err)
+		}
+		log.Printf("Converting maps for %s", target.Name())
+		defaultKeyStrategy, mapKeyStrategies, err := parseKeyStrategies(keyStrategies)
+		if err != nil {
+			log.Fatalf("Could not parse -keys: %v\n", err)
+		}
+		mapNameScheme, err := rtlfile.ParseMapNameScheme(mapNames)
+		if err != nil {
+			log.Fatalf("Could not parse -map-names: %v\n", err)
+		}
+		mapFormat, err := quakemap.ParseMapFormat(mapFormatName)
+		if err != nil {
+			log.Fatalf("Could not parse -map-format: %v\n", err)
 		}
 		if err := os.MkdirAll(rtlMapOutdir, 0755); err != nil {
 			log.Fatalf("Could not create outdir: %v\n", err)
@@ -323,12 +599,19 @@ func main() {
 				continue
 			}
 
+			rtl.MapData[idx].KeyStrategy = defaultKeyStrategy
+			rtl.MapData[idx].MapNameScheme = mapNameScheme
+			rtl.MapData[idx].BossHealthScale = bossHealthScale
+			if strategy, ok := mapKeyStrategies[idx+1]; ok {
+				rtl.MapData[idx].KeyStrategy = strategy
+			}
+
 			log.Printf("Generating map%03d (%s)...", idx+1, md.MapName())
 			rtlMapFile := fmt.Sprintf("%s/map%03d.txt", rtlMapOutdir, idx+1)
 			rtlRawWallFile := fmt.Sprintf("%s/map%03d-walls.bin", rtlMapOutdir, idx+1)
 			rtlRawSpriteFile := fmt.Sprintf("%s/map%03d-sprites.bin", rtlMapOutdir, idx+1)
 			rtlRawInfoFile := fmt.Sprintf("%s/map%03d-info.bin", rtlMapOutdir, idx+1)
-			rtlQuakeMapFile := fmt.Sprintf("%s/map%03d.map", rtlMapOutdir, idx+1)
+			rtlQuakeMapFile := fmt.Sprintf("%s/%s.map", rtlMapOutdir, mapNameScheme.MapFileName(idx+1))
 			rtlHtmlFile := fmt.Sprintf("%s/map%03d.html", rtlMapOutdir, idx+1)
 
 			wallFhnd, err := os.Create(rtlMapFile)
@@ -373,11 +656,17 @@ func main() {
 				log.Fatalf("Could not open %s for writing: %v\n", rtlQuakeMapFile, err)
 			}
 			defer quakeMapFhnd.Close()
-			qm := rtlfile.ConvertRTLMapToQuakeMapFile(&rtl.MapData[idx], wadOut, rtlMapScale, convertToDusk, additionalWads[:], fgdFile)
+			qm := rtlfile.ConvertRTLMapToQuakeMapFile(&rtl.MapData[idx], wadOut, rtlMapScale, target, additionalWads[:], fgdFile)
+			qm.Format = mapFormat
 			if _, err = quakeMapFhnd.Write([]byte(qm.Render())); err != nil {
 				log.Fatalf("Could not write quake map file to %s: %v\n", rtlQuakeMapFile, err)
 			}
 
+			if udmfOut != "" {
+				udmfMaps = append(udmfMaps, rtlfile.ConvertRTLMapToUDMF(&rtl.MapData[idx]))
+				udmfMapNames = append(udmfMapNames, rtl.MapData[idx].DoomMapName())
+			}
+
 			htmlFhnd, err := os.Create(rtlHtmlFile)
 			if err != nil {
 				log.Fatalf("Could not open %s for writing: %v\n", rtlHtmlFile, err)
@@ -387,6 +676,19 @@ func main() {
 				log.Fatalf("Could not write quake map file to %s: %v\n", rtlHtmlFile, err)
 			}
 		}
+
+		startMapFile := fmt.Sprintf("%s/%s.map", rtlMapOutdir, rtlfile.StartMapName)
+		log.Printf("Generating %s...", startMapFile)
+		startFhnd, err := os.Create(startMapFile)
+		if err != nil {
+			log.Fatalf("Could not open %s for writing: %v\n", startMapFile, err)
+		}
+		defer startFhnd.Close()
+		qm := rtlfile.CreateStartMap(rtl, wadOut, rtlMapScale, mapNameScheme, target, additionalWads[:], fgdFile)
+		qm.Format = mapFormat
+		if _, err = startFhnd.Write([]byte(qm.Render())); err != nil {
+			log.Fatalf("Could not write quake map file to %s: %v\n", startMapFile, err)
+		}
 	}
 
 	fhnd, err := os.Open(flag.Arg(0))
@@ -420,6 +722,15 @@ func main() {
 		fmt.Printf("WAD file has %d lumps\n", len(rottWad.LumpDirectory))
 	}
 
+	if udmfOut != "" {
+		if rtlMapOutdir == "" {
+			log.Fatalf("-udmf-out requires -rtl-map-outdir\n")
+		}
+		if err := writeUDMFPWAD(wadExtractor, udmfMaps, udmfMapNames, udmfOut); err != nil {
+			log.Fatalf("Could not write PWAD file %s: %v\n", udmfOut, err)
+		}
+	}
+
 	if printLumps {
 		iter := wadExtractor.List()
 		for entry := iter.Next(); entry != nil; entry = iter.Next() {
@@ -512,5 +823,18 @@ func main() {
 				fmt.Printf("Wad file %s written (%d bytes)\n", wadOut, wad2written)
 			}
 		}
+
+		if walOut != "" {
+			if wad2Out == nil || quake2PalettePath == "" {
+				log.Fatalf("-wal-out requires -wad-out and -q2-palette\n")
+			}
+			palette, err := loadQuake2Palette(quake2PalettePath)
+			if err != nil {
+				log.Fatalf("Could not load Quake 2 palette from %s: %v\n", quake2PalettePath, err)
+			}
+			if err := writeWALTextures(wad2Out, walOut, palette); err != nil {
+				log.Fatalf("Could not write .wal textures: %v\n", err)
+			}
+		}
 	}
 }
@@ -1,6 +1,7 @@
 package imgutil
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"log"
@@ -81,6 +82,16 @@ func BuildPalette(data []byte, transparencyIdx int) color.Palette {
 	return pal
 }
 
+// returns the palette at the end of a 256 color PCX image, the way
+// Quake 2 stores its palette in pics/colormap.pcx
+func PCXPalette(data []byte) (color.Palette, error) {
+	// 0x0c marker followed by 256 RGB triplets
+	if len(data) < 769 || data[len(data)-769] != 0x0c {
+		return nil, fmt.Errorf("no 256 color palette found in PCX data")
+	}
+	return BuildPalette(data[len(data)-768:], -1), nil
+}
+
 func GetPalette(gameName string) *color.Palette {
 	var name2pal = map[string]color.Palette{
 		"rott":  RottPalette,
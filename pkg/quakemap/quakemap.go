@@ -3,6 +3,7 @@ package quakemap
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 )
 
@@ -10,8 +11,61 @@ var (
 	SPAWNFLAG_NotOnEasy   int = 256
 	SPAWNFLAG_NotOnNormal int = 512
 	SPAWNFLAG_NotOnHard   int = 1024
+
+	// monsters only wake up on seeing the player or being fired
+	SPAWNFLAG_Ambush int = 1
+)
+
+// Quake 2 surface flags and contents (q_shared.h)
+var (
+	SURF_Light   int = 0x1
+	SURF_Sky     int = 0x4
+	SURF_Warp    int = 0x8
+	SURF_Trans33 int = 0x10
+	SURF_Trans66 int = 0x20
+	SURF_NoDraw  int = 0x80
+
+	CONTENTS_Solid       int = 0x1
+	CONTENTS_Window      int = 0x2
+	CONTENTS_Mist        int = 0x40
+	CONTENTS_PlayerClip  int = 0x10000
+	CONTENTS_MonsterClip int = 0x20000
+	CONTENTS_Detail      int = 0x8000000
+	CONTENTS_Translucent int = 0x10000000
+)
+
+type MapFormat int
+
+const (
+	// original Quake texture projection: offset, rotation and scale
+	// on axes picked from the face's normal
+	FORMAT_Standard MapFormat = iota
+	// Valve 220: explicit texture axes on every face
+	FORMAT_Valve220
 )
 
+func (f MapFormat) String() string {
+	switch f {
+	case FORMAT_Standard:
+		return "standard"
+	case FORMAT_Valve220:
+		return "valve220"
+	default:
+		return fmt.Sprintf("MapFormat(%d)", int(f))
+	}
+}
+
+func ParseMapFormat(name string) (MapFormat, error) {
+	switch name {
+	case "standard":
+		return FORMAT_Standard, nil
+	case "valve220":
+		return FORMAT_Valve220, nil
+	default:
+		return FORMAT_Standard, fmt.Errorf("unknown map format %q (expected standard or valve220)", name)
+	}
+}
+
 type Plane struct {
 	X1, Y1, Z1       float64
 	X2, Y2, Z2       float64
@@ -20,6 +74,90 @@ type Plane struct {
 	Xoffset, Yoffset float64
 	Rotation         float64
 	Xscale, Yscale   float64
+	// texture axes for Valve 220 output, derived from the normal,
+	// rotation and offsets when left empty
+	UAxis, VAxis [3]float64
+	// Quake 2 only
+	Contents, SurfaceFlags, Value int
+}
+
+// rows of the normal, U and V axes Quake projects textures along
+// (TextureAxisFromPlane in the Quake tools), first match wins
+var baseTextureAxes = [][3][3]float64{
+	{{0, 0, 1}, {1, 0, 0}, {0, -1, 0}},  // floor
+	{{0, 0, -1}, {1, 0, 0}, {0, -1, 0}}, // ceiling
+	{{1, 0, 0}, {0, 1, 0}, {0, 0, -1}},  // west wall
+	{{-1, 0, 0}, {0, 1, 0}, {0, 0, -1}}, // east wall
+	{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}},  // south wall
+	{{0, -1, 0}, {1, 0, 0}, {0, 0, -1}}, // north wall
+}
+
+func dotProduct(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+// returns the (unnormalized) normal of the plane the way the Quake
+// tools derive it from the three points
+func (p *Plane) Normal() [3]float64 {
+	t1 := [3]float64{p.X1 - p.X2, p.Y1 - p.Y2, p.Z1 - p.Z2}
+	t2 := [3]float64{p.X3 - p.X2, p.Y3 - p.Y2, p.Z3 - p.Z2}
+	return [3]float64{
+		t1[1]*t2[2] - t1[2]*t2[1],
+		t1[2]*t2[0] - t1[0]*t2[2],
+		t1[0]*t2[1] - t1[1]*t2[0],
+	}
+}
+
+// returns the unrotated U and V axes of the standard projection
+func (p *Plane) baseAxes() ([3]float64, [3]float64) {
+	normal := p.Normal()
+	best := 0
+	bestDot := 0.0
+	for i, axes := range baseTextureAxes {
+		if dot := dotProduct(normal, axes[0]); dot > bestDot {
+			best = i
+			bestDot = dot
+		}
+	}
+	return baseTextureAxes[best][1], baseTextureAxes[best][2]
+}
+
+// returns the texture axes of the plane, either the ones set or the
+// ones the standard projection would use with its rotation
+func (p *Plane) TextureAxes() ([3]float64, [3]float64) {
+	if p.UAxis != ([3]float64{}) && p.VAxis != ([3]float64{}) {
+		return p.UAxis, p.VAxis
+	}
+	uAxis, vAxis := p.baseAxes()
+	if p.Rotation == 0 {
+		return uAxis, vAxis
+	}
+
+	// rotate within the two components the base axes use
+	var sv, tv int
+	for i := 0; i < 3; i++ {
+		if uAxis[i] != 0 {
+			sv = i
+		}
+		if vAxis[i] != 0 {
+			tv = i
+		}
+	}
+	sinv := math.Sin(p.Rotation * math.Pi / 180.0)
+	cosv := math.Cos(p.Rotation * math.Pi / 180.0)
+	rotate := func(axis [3]float64) [3]float64 {
+		s, t := axis[sv], axis[tv]
+		axis[sv] = cosv*s - sinv*t
+		axis[tv] = sinv*s + cosv*t
+		for i := range axis {
+			// keep right angles exact
+			if math.Abs(axis[i]) < 1e-9 {
+				axis[i] = 0
+			}
+		}
+		return axis
+	}
+	return rotate(uAxis), rotate(vAxis)
 }
 
 func (p *Plane) Clone() Plane {
@@ -39,22 +177,63 @@ func (p *Plane) Clone() Plane {
 	notp.Rotation = p.Rotation
 	notp.Xscale = p.Xscale
 	notp.Yscale = p.Yscale
+	notp.UAxis = p.UAxis
+	notp.VAxis = p.VAxis
+	notp.Contents = p.Contents
+	notp.SurfaceFlags = p.SurfaceFlags
+	notp.Value = p.Value
 	return notp
 }
 
-func (p *Plane) Render() string {
+// surfaceFields adds Quake 2's contents, surface flags and value
+func (p *Plane) Render(format MapFormat, surfaceFields bool) string {
+	surface := ""
+	if surfaceFields {
+		surface = fmt.Sprintf(" %d %d %d", p.Contents, p.SurfaceFlags, p.Value)
+	}
+
 	texture := p.Texture
 	if texture == "" {
 		texture = "__TB_empty"
 	}
-	return fmt.Sprintf("(%.02f %.02f %.02f) (%.02f %.02f %.02f) (%.02f %.02f %.02f) %s %.02f %.02f %.02f %.02f %.02f",
+	points := fmt.Sprintf("(%.02f %.02f %.02f) (%.02f %.02f %.02f) (%.02f %.02f %.02f)",
 		p.X1, p.Y1, p.Z1,
 		p.X2, p.Y2, p.Z2,
 		p.X3, p.Y3, p.Z3,
+	)
+
+	if format == FORMAT_Valve220 {
+		uAxis, vAxis := p.TextureAxes()
+		return fmt.Sprintf("%s %s [ %g %g %g %.02f ] [ %g %g %g %.02f ] %.02f %.02f %.02f%s",
+			points,
+			texture,
+			uAxis[0], uAxis[1], uAxis[2], p.Xoffset,
+			vAxis[0], vAxis[1], vAxis[2], p.Yoffset,
+			p.Rotation,
+			p.Xscale, p.Yscale,
+			surface,
+		)
+	}
+
+	// the standard format can only mirror the base axes, through
+	// negative scales
+	xScale, yScale := p.Xscale, p.Yscale
+	if p.UAxis != ([3]float64{}) && p.VAxis != ([3]float64{}) {
+		baseU, baseV := p.baseAxes()
+		if dotProduct(p.UAxis, baseU) < 0 {
+			xScale = -xScale
+		}
+		if dotProduct(p.VAxis, baseV) < 0 {
+			yScale = -yScale
+		}
+	}
+	return fmt.Sprintf("%s %s %.02f %.02f %.02f %.02f %.02f%s",
+		points,
 		texture,
 		p.Xoffset, p.Yoffset,
 		p.Rotation,
-		p.Xscale, p.Yscale,
+		xScale, yScale,
+		surface,
 	)
 }
 
@@ -204,10 +383,10 @@ func (b *Brush) Translate(x, y, z float64) {
 	}
 }
 
-func (b *Brush) Render() string {
+func (b *Brush) Render(format MapFormat, surfaceFields bool) string {
 	out := "{\n"
 	for _, plane := range b.Planes {
-		out += plane.Render() + "\n"
+		out += plane.Render(format, surfaceFields) + "\n"
 	}
 	out += "}\n"
 	return out
@@ -321,14 +500,40 @@ func (e *Entity) Height() float64 {
 	return vertMax - vertMin
 }
 
+// returns the opposite corners of the box around all brushes
+func (e *Entity) Bounds() (float64, float64, float64, float64, float64, float64) {
+	minX, minY, minZ := math.Inf(1), math.Inf(1), math.Inf(1)
+	maxX, maxY, maxZ := math.Inf(-1), math.Inf(-1), math.Inf(-1)
+	for _, brush := range e.Brushes {
+		for _, plane := range brush.Planes {
+			for _, point := range [][3]float64{
+				{plane.X1, plane.Y1, plane.Z1},
+				{plane.X2, plane.Y2, plane.Z2},
+				{plane.X3, plane.Y3, plane.Z3},
+			} {
+				minX, maxX = math.Min(minX, point[0]), math.Max(maxX, point[0])
+				minY, maxY = math.Min(minY, point[1]), math.Max(maxY, point[1])
+				minZ, maxZ = math.Min(minZ, point[2]), math.Max(maxZ, point[2])
+			}
+		}
+	}
+	return minX, minY, minZ, maxX, maxY, maxZ
+}
+
 func (e *Entity) Render() string {
 	output := fmt.Sprintf(`{
 "spawnflags" "%d"
 "classname" "%s"
 `, e.SpawnFlags, e.ClassName)
 
-	for k, v := range e.AdditionalKeys {
-		output += fmt.Sprintf("\"%s\" \"%s\"\n", k, v)
+	// sorted so the same map always renders the same way
+	var keys []string
+	for k := range e.AdditionalKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		output += fmt.Sprintf("\"%s\" \"%s\"\n", k, e.AdditionalKeys[k])
 	}
 
 	if e.ClassName != "worldspawn" {
@@ -339,15 +544,25 @@ func (e *Entity) Render() string {
 		}
 	}
 
+	format := FORMAT_Standard
+	surfaceFields := false
+	if e.Map != nil {
+		format = e.Map.Format
+		surfaceFields = e.Map.SurfaceFields
+	}
+
 	switch e.ClassName {
 	case "worldspawn":
 		output += fmt.Sprintf("\"wad\" \"%s\"\n", strings.Join(e.Map.Wads, ";"))
+		if format == FORMAT_Valve220 {
+			output += "\"mapversion\" \"220\"\n"
+		}
 	}
 
 	if len(e.Brushes) > 0 {
 		for idx, brush := range e.Brushes {
 			output += fmt.Sprintf("// brush %d\n", idx)
-			output += brush.Render() + "\n"
+			output += brush.Render(format, surfaceFields) + "\n"
 		}
 	}
 	output += "}\n"
@@ -355,6 +570,8 @@ func (e *Entity) Render() string {
 }
 
 type QuakeMap struct {
+	Format          MapFormat
+	SurfaceFields   bool // write Quake 2 surface fields
 	Wads            []string
 	WorldSpawn      *Entity
 	InfoPlayerStart *Entity
@@ -1,6 +1,7 @@
 package quakemap
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -28,6 +29,30 @@ func TestQuakeMapWithBrushes(t *testing.T) {
 		ClassName:  "foobar",
 		Brushes:    []Brush{b},
 	}
-	q.Entities = append(q.Entities, e)
+	q.Entities = append(q.Entities, &e)
 	t.Log(q.Render())
 }
+
+func TestQuakeMapValve220(t *testing.T) {
+	q := NewQuakeMap(7, 8, 9)
+	q.Format = FORMAT_Valve220
+	params := BasicCuboidParams("footexbar", 1.0, false)
+	params.FlipSides()
+	q.WorldSpawn.AddBrush(BuildCuboidBrush(0, 0, 0, 64, 64, 64, params))
+
+	output := q.Render()
+	if !strings.Contains(output, `"mapversion" "220"`) {
+		t.Errorf("mapversion missing from worldspawn")
+	}
+	// flipped south face
+	if !strings.Contains(output, "footexbar [ -1 0 0 0.00 ] [ 0 0 -1 0.00 ]") {
+		t.Errorf("flipped texture axes missing")
+	}
+	t.Log(output)
+
+	// the standard format mirrors through the scale instead
+	q.Format = FORMAT_Standard
+	if !strings.Contains(q.Render(), "footexbar 0.00 0.00 0.00 -1.00 1.00") {
+		t.Errorf("flipped texture scale missing")
+	}
+}
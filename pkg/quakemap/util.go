@@ -3,6 +3,24 @@ package quakemap
 type FaceParams struct {
 	Texture              string
 	TexScaleX, TexScaleY float64
+	UAxis, VAxis         [3]float64 // see Plane
+}
+
+func (f *FaceParams) SetAxes(uAxis, vAxis [3]float64) {
+	f.UAxis = uAxis
+	f.VAxis = vAxis
+}
+
+// mirrors the texture horizontally by reversing its U axis
+func (f *FaceParams) FlipHorizontally(defaultU, defaultV [3]float64) {
+	if f.UAxis == ([3]float64{}) || f.VAxis == ([3]float64{}) {
+		f.SetAxes(defaultU, defaultV)
+	}
+	for i := range f.UAxis {
+		if f.UAxis[i] != 0 {
+			f.UAxis[i] = -f.UAxis[i]
+		}
+	}
 }
 
 // a cube(-ish) brush with 6 sides, no rotation,
@@ -11,6 +29,34 @@ type CuboidParams struct {
 	North, South, East, West, Top, Bottom FaceParams
 }
 
+// texture axes of the standard projection for each side of a cuboid
+var (
+	NorthSouthAxisU = [3]float64{1, 0, 0}
+	NorthSouthAxisV = [3]float64{0, 0, -1}
+	EastWestAxisU   = [3]float64{0, 1, 0}
+	EastWestAxisV   = [3]float64{0, 0, -1}
+	TopBottomAxisU  = [3]float64{1, 0, 0}
+	TopBottomAxisV  = [3]float64{0, -1, 0}
+)
+
+// sets every face's axes to the ones the standard projection uses
+func (c *CuboidParams) SetDefaultAxes() {
+	c.North.SetAxes(NorthSouthAxisU, NorthSouthAxisV)
+	c.South.SetAxes(NorthSouthAxisU, NorthSouthAxisV)
+	c.East.SetAxes(EastWestAxisU, EastWestAxisV)
+	c.West.SetAxes(EastWestAxisU, EastWestAxisV)
+	c.Top.SetAxes(TopBottomAxisU, TopBottomAxisV)
+	c.Bottom.SetAxes(TopBottomAxisU, TopBottomAxisV)
+}
+
+// mirrors the textures on the four sides horizontally
+func (c *CuboidParams) FlipSides() {
+	c.North.FlipHorizontally(NorthSouthAxisU, NorthSouthAxisV)
+	c.South.FlipHorizontally(NorthSouthAxisU, NorthSouthAxisV)
+	c.East.FlipHorizontally(EastWestAxisU, EastWestAxisV)
+	c.West.FlipHorizontally(EastWestAxisU, EastWestAxisV)
+}
+
 func BasicCuboidParams(texture string, scale float64, wrapTexture bool) CuboidParams {
 	var params CuboidParams
 
@@ -59,6 +105,13 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		z2 = tmp
 	}
 
+	// applies the face's texture axes to the plane just added
+	setAxes := func(face FaceParams) {
+		plane := &b.Planes[len(b.Planes)-1]
+		plane.UAxis = face.UAxis
+		plane.VAxis = face.VAxis
+	}
+
 	// south
 	b.AddPlane(
 		x1, y1, z2, // p1
@@ -68,6 +121,7 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		0, 0, // offset
 		0, // rotation
 		params.South.TexScaleX, params.South.TexScaleY)
+	setAxes(params.South)
 	// north
 	b.AddPlane(
 		x2, y2, z2, // p1
@@ -77,6 +131,7 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		0, 0, // offset
 		0, // rotation
 		params.North.TexScaleX, params.North.TexScaleY)
+	setAxes(params.North)
 	// west
 	b.AddPlane(
 		x1, y2, z2, // p1
@@ -86,6 +141,7 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		0, 0, // offset
 		0, // rotation
 		params.West.TexScaleX, params.West.TexScaleY)
+	setAxes(params.West)
 	// east
 	b.AddPlane(
 		x2, y1, z2, // p1
@@ -95,6 +151,7 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		0, 0, // offset
 		0, // rotation
 		params.East.TexScaleX, params.East.TexScaleY)
+	setAxes(params.East)
 	// top
 	b.AddPlane(
 		x1, y1, z2, // p1
@@ -104,6 +161,7 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		0, 0, // offset
 		0, // rotation
 		params.Top.TexScaleX, params.Top.TexScaleY)
+	setAxes(params.Top)
 	// bottom
 	b.AddPlane(
 		x1, y1, z1, // p1
@@ -113,6 +171,7 @@ func BuildCuboidBrush(x1, y1, z1, x2, y2, z2 float64, params CuboidParams) Brush
 		0, 0, // offset
 		0, // rotation
 		params.Bottom.TexScaleX, params.Bottom.TexScaleY)
+	setAxes(params.Bottom)
 
 	return b
 }
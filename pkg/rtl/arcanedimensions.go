@@ -0,0 +1,97 @@
+package rtl
+
+// Arcane Dimensions, a Quake mod with a much larger entity set than
+// vanilla Quake
+
+import (
+	"fmt"
+
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
+const (
+	// func_breakable debris
+	ADBreakableGlass = 3
+)
+
+func init() {
+	RegisterTargetProfile(ArcaneDimensionsProfile{})
+	if err := arcaneDimensionsMapping.Apply(); err != nil {
+		panic(fmt.Sprintf("invalid Arcane Dimensions mapping: %v", err))
+	}
+}
+
+// Behaves like vanilla Quake apart from breakables, everything else is
+// in the entity mapping below.
+type ArcaneDimensionsProfile struct {
+	QuakeProfile
+}
+
+func (ArcaneDimensionsProfile) Name() string {
+	return "ad"
+}
+
+func (ArcaneDimensionsProfile) HasBreakables() bool {
+	return true
+}
+
+func (ArcaneDimensionsProfile) SetupBreakable(entity *quakemap.Entity) {
+	entity.AdditionalKeys["style"] = fmt.Sprintf("%d", ADBreakableGlass)
+	entity.AdditionalKeys["health"] = "1"
+	entity.AdditionalKeys["alpha"] = fmt.Sprintf("%.02f", GlassAlpha)
+}
+
+func adItem(name string, keys map[string]string) *ItemTargetConfig {
+	return &ItemTargetConfig{Name: name, Keys: keys, KeepCallback: true}
+}
+
+func adMonsters(names ...string) []EntityChoice {
+	var choices []EntityChoice
+	for _, name := range names {
+		choices = append(choices, EntityChoice{Name: name})
+	}
+	return choices
+}
+
+// items and enemies without an entry here use their Quake entities
+var arcaneDimensionsMapping = MappingConfig{
+	Items: map[string]ItemConfig{
+		// one-up, three-up
+		"0x28": {Targets: map[string]*ItemTargetConfig{"ad": adItem("item_health", map[string]string{"spawnflags": "2"})}},
+		"0x29": {Targets: map[string]*ItemTargetConfig{"ad": adItem("item_health", map[string]string{"spawnflags": "2"})}},
+		// trampolines
+		"0xc1": {Targets: map[string]*ItemTargetConfig{"ad": adItem("trigger_push", nil)}},
+		// rotating blades
+		"0xae": {Targets: map[string]*ItemTargetConfig{"ad": adItem("trap_sawbladey", map[string]string{"dmg": "10"})}},
+		// spikes, crushing columns
+		fmt.Sprintf("%d", SpikesUp):    {Targets: map[string]*ItemTargetConfig{"ad": adItem("func_bob", nil)}},
+		fmt.Sprintf("%d", SpikesDown):  {Targets: map[string]*ItemTargetConfig{"ad": adItem("func_bob", nil)}},
+		fmt.Sprintf("%d", CrusherUp):   {Targets: map[string]*ItemTargetConfig{"ad": adItem("func_bob", nil)}},
+		fmt.Sprintf("%d", CrusherDown): {Targets: map[string]*ItemTargetConfig{"ad": adItem("func_bob", nil)}},
+		// flamethrowers, firing up
+		"0x186": {Targets: map[string]*ItemTargetConfig{"ad": adItem("trap_gasshooter", map[string]string{"angle": "-1", "style": "2"})}},
+		// firepit, with Quake's large flame. Light posts and vases have
+		// no stock model to show with misc_model and are left out like in
+		// vanilla Quake
+		"0x40": {Targets: map[string]*ItemTargetConfig{"ad": adItem("misc_model", map[string]string{"mdl": "progs/flame2.mdl", "frame": "1"})}},
+	},
+	Enemies: map[string]EnemyConfig{
+		"low_guard":        {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_army")}},
+		"sneaky_low_guard": {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_army")}},
+		"high_guard":       {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_enforcer")}},
+		"overpatrol_guard": {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_army")}},
+		"strike_guard":     {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_enforcer")}},
+		"triad_enforcer":   {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_defender")}},
+		"lightning_guard":  {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_eliminator")}},
+		"monk":             {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_knight")}},
+		"fire_monk":        {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_skullwiz")}},
+		"robo_guard":       {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_centurion")}},
+		"ballistikraft":    {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_jim")}},
+		"gun_emplacement":  {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_enforcer")}},
+		"4_way_gun":        {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_enforcer")}},
+		"general_darian":   {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_minotaur")}},
+		"sebastian_krist":  {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_golem")}},
+		"nme":              {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_boglord")}},
+		"el_oscuro":        {Targets: map[string][]EntityChoice{"ad": adMonsters("monster_shalrath")}},
+	},
+}
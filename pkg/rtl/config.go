@@ -0,0 +1,178 @@
+package rtl
+
+// Loading the item, enemy, door and masked wall tables from JSON, on
+// top of the built-in defaults
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// replacement entity of an item for one target
+type ItemTargetConfig struct {
+	Name    string            `json:"name,omitempty"`
+	Height  *float64          `json:"height,omitempty"`
+	ZOffset *float64          `json:"z_offset,omitempty"`
+	Keys    map[string]string `json:"keys,omitempty"`
+	// spawn the replacement entity through the item's built-in
+	// callback rather than as a plain point entity, overrides can't
+	// turn it off again
+	KeepCallback bool `json:"keep_callback,omitempty"`
+}
+
+// returns the config with the fields set in the override replacing
+// its own, keys are merged one by one. Built-in target mappings are
+// applied first, so user overrides only change what they set.
+func (config *ItemTargetConfig) merge(override *ItemTargetConfig) *ItemTargetConfig {
+	if config == nil {
+		return override
+	}
+	if override == nil {
+		return config
+	}
+	merged := *config
+	if override.Name != "" {
+		merged.Name = override.Name
+	}
+	if override.Height != nil {
+		merged.Height = override.Height
+	}
+	if override.ZOffset != nil {
+		merged.ZOffset = override.ZOffset
+	}
+	if len(override.Keys) > 0 {
+		merged.Keys = make(map[string]string)
+		for k, v := range config.Keys {
+			merged.Keys[k] = v
+		}
+		for k, v := range override.Keys {
+			merged.Keys[k] = v
+		}
+	}
+	merged.KeepCallback = config.KeepCallback || override.KeepCallback
+	return &merged
+}
+
+type ItemConfig struct {
+	Targets      map[string]*ItemTargetConfig `json:"targets,omitempty"`
+	PlaceOnFloor *bool                        `json:"place_on_floor,omitempty"`
+}
+
+type EnemyConfig struct {
+	Targets map[string][]EntityChoice `json:"targets,omitempty"`
+	Boss    *bool                     `json:"boss,omitempty"`
+}
+
+// Items and masked walls are keyed by their ROTT sprite or tile ID,
+// door textures by door number (see GetDoorTextures), all either
+// decimal or 0x prefixed hex. Enemies are keyed by their name in
+// Enemies. Item and enemy entries only replace the fields they set,
+// their targets are keyed by target name (see TargetProfile).
+type MappingConfig struct {
+	Seed        *int64                    `json:"seed,omitempty"`
+	Items       map[string]ItemConfig     `json:"items,omitempty"`
+	Enemies     map[string]EnemyConfig    `json:"enemies,omitempty"`
+	Doors       map[string]DoorTexInfo    `json:"doors,omitempty"`
+	MaskedWalls map[string]MaskedWallInfo `json:"masked_walls,omitempty"`
+}
+
+func parseTableID(key string) (uint16, error) {
+	id, err := strconv.ParseUint(key, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q: %v", key, err)
+	}
+	return uint16(id), nil
+}
+
+// returns an error if no target of the name is registered
+func checkTargetName(name string) error {
+	_, err := GetTargetProfile(name)
+	return err
+}
+
+// Loads a mapping config and applies it to the conversion tables. Must
+// be called before the RTL file is read.
+func LoadMappingConfig(r io.Reader) error {
+	var config MappingConfig
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("could not parse mapping config: %v", err)
+	}
+	return config.Apply()
+}
+
+// applies the config to the conversion tables, also used by targets to
+// add their built-in mappings
+func (config *MappingConfig) Apply() error {
+	if config.Seed != nil {
+		seed := *config.Seed
+		EntitySeed = &seed
+	}
+
+	for key, itemConfig := range config.Items {
+		id, err := parseTableID(key)
+		if err != nil {
+			return fmt.Errorf("items: %v", err)
+		}
+		item, ok := Items[id]
+		if !ok {
+			item = ItemInfo{SpriteId: id}
+		}
+		for targetName, targetConfig := range itemConfig.Targets {
+			if err := checkTargetName(targetName); err != nil {
+				return fmt.Errorf("items: %v", err)
+			}
+			if item.Targets == nil {
+				item.Targets = make(map[string]*ItemTargetConfig)
+			}
+			item.Targets[targetName] = item.Targets[targetName].merge(targetConfig)
+		}
+		if itemConfig.PlaceOnFloor != nil {
+			item.PlaceOnFloor = *itemConfig.PlaceOnFloor
+		}
+		Items[id] = item
+	}
+
+	for name, enemyConfig := range config.Enemies {
+		enemy, ok := Enemies[name]
+		if !ok {
+			return fmt.Errorf("enemies: unknown enemy %q", name)
+		}
+		if enemy.Choices == nil {
+			enemy.Choices = make(map[string][]EntityChoice)
+		}
+		for targetName, choices := range enemyConfig.Targets {
+			if err := checkTargetName(targetName); err != nil {
+				return fmt.Errorf("enemies: %v", err)
+			}
+			if len(choices) > 0 {
+				enemy.Choices[targetName] = choices
+			}
+		}
+		if enemyConfig.Boss != nil {
+			enemy.Boss = *enemyConfig.Boss
+		}
+		Enemies[name] = enemy
+	}
+
+	for key, door := range config.Doors {
+		id, err := parseTableID(key)
+		if err != nil {
+			return fmt.Errorf("doors: %v", err)
+		}
+		DoorTextures[id] = door
+	}
+
+	for key, maskedWall := range config.MaskedWalls {
+		id, err := parseTableID(key)
+		if err != nil {
+			return fmt.Errorf("masked_walls: %v", err)
+		}
+		MaskedWalls[id] = maskedWall
+	}
+
+	return nil
+}
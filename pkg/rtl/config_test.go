@@ -0,0 +1,223 @@
+package rtl
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// restores the tables LoadMappingConfig changes once the test is done
+func restoreMappingTables(t *testing.T) {
+	items := make(map[uint16]ItemInfo)
+	for k, v := range Items {
+		items[k] = v
+	}
+	enemies := make(map[string]EnemyConversionInfo)
+	for k, v := range Enemies {
+		choices := make(map[string][]EntityChoice)
+		for target, targetChoices := range v.Choices {
+			choices[target] = targetChoices
+		}
+		v.Choices = choices
+		enemies[k] = v
+	}
+	seed := EntitySeed
+	t.Cleanup(func() {
+		Items = items
+		Enemies = enemies
+		EntitySeed = seed
+	})
+}
+
+func TestLoadMappingConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"empty", `{}`, ""},
+		{"item", `{"items": {"0x2e": {"targets": {"quake": {"name": "weapon_supernailgun", "keys": {"spawnflags": "1"}}}}}}`, ""},
+		{"decimal item", `{"items": {"46": {"place_on_floor": true}}}`, ""},
+		{"enemy", `{"seed": 42, "enemies": {"low_guard": {"targets": {"quake": [{"name": "monster_army", "weight": 3}, {"name": "monster_enforcer"}]}}}}`, ""},
+		{"unknown field", `{"itmes": {}}`, "unknown field"},
+		{"unknown item field", `{"items": {"0x2e": {"name": "weapon_supernailgun"}}}`, "unknown field"},
+		{"unknown item target", `{"items": {"0x2e": {"targets": {"doom": {"name": "weapon_chaingun"}}}}}`, "unknown target"},
+		{"unknown enemy target", `{"enemies": {"low_guard": {"targets": {"doom": [{"name": "zombieman"}]}}}}`, "unknown target"},
+		{"unknown enemy", `{"enemies": {"no_guard": {}}}`, "unknown enemy"},
+		{"invalid item ID", `{"items": {"bat": {}}}`, "invalid ID"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restoreMappingTables(t)
+			err := LoadMappingConfig(strings.NewReader(test.config))
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadMappingConfigApplies(t *testing.T) {
+	restoreMappingTables(t)
+	config := `{
+		"seed": 7,
+		"items": {"0x2e": {"targets": {"quake": {"name": "weapon_supernailgun", "keys": {"spawnflags": "1"}}}}},
+		"enemies": {"low_guard": {"targets": {"quake": [{"name": "monster_enforcer"}]}, "boss": true}}
+	}`
+	if err := LoadMappingConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	quake, _ := GetTargetProfile("quake")
+
+	item := Items[0x2e]
+	if name := item.EntityName(quake); name != "weapon_supernailgun" {
+		t.Errorf("item entity: expected weapon_supernailgun, got %q", name)
+	}
+	if keys := item.EntityKeys(quake); keys["spawnflags"] != "1" {
+		t.Errorf("item keys: expected spawnflags 1, got %v", keys)
+	}
+
+	enemy := Enemies["low_guard"]
+	if !enemy.Boss {
+		t.Errorf("enemy: expected boss")
+	}
+	if choice := enemy.EntityChoice(&ActorInfo{X: 3, Y: 4}, quake); choice == nil || choice.Name != "monster_enforcer" {
+		t.Errorf("enemy entity: expected monster_enforcer, got %v", choice)
+	}
+	if EntitySeed == nil || *EntitySeed != 7 {
+		t.Errorf("expected seed 7, got %v", EntitySeed)
+	}
+}
+
+func TestLoadMappingConfigMergesTargets(t *testing.T) {
+	restoreMappingTables(t)
+	// the built-in AD mapping spawns trampolines through their callback
+	config := `{"items": {"0xc1": {"targets": {"ad": {"keys": {"speed": "500"}}}}}}`
+	if err := LoadMappingConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	ad, _ := GetTargetProfile("ad")
+	item := Items[0xc1]
+	if !item.UsesCallback(ad) {
+		t.Errorf("expected the override to keep the built-in callback")
+	}
+	if name := item.EntityName(ad); name != "trigger_push" {
+		t.Errorf("expected the built-in trigger_push, got %q", name)
+	}
+	if keys := item.EntityKeys(ad); keys["speed"] != "500" {
+		t.Errorf("expected speed 500, got %v", keys)
+	}
+}
+
+// counts the entities picked across the whole map
+func countEntityChoices(enemy *EnemyConversionInfo, target TargetProfile) map[string]int {
+	counts := make(map[string]int)
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			counts[enemy.EntityChoice(&ActorInfo{X: x, Y: y}, target).Name]++
+		}
+	}
+	return counts
+}
+
+func TestEntityChoiceWeights(t *testing.T) {
+	restoreMappingTables(t)
+	quake, _ := GetTargetProfile("quake")
+	tests := []struct {
+		name    string
+		choices []EntityChoice
+		shares  map[string]float64
+	}{
+		{"single", []EntityChoice{{Name: "monster_army"}}, map[string]float64{"monster_army": 1.0}},
+		{"3 to 1", []EntityChoice{{Name: "monster_army", Weight: 3}, {Name: "monster_enforcer"}},
+			map[string]float64{"monster_army": 0.75, "monster_enforcer": 0.25}},
+		{"even", []EntityChoice{{Name: "monster_army", Weight: 2}, {Name: "monster_enforcer", Weight: 2}},
+			map[string]float64{"monster_army": 0.5, "monster_enforcer": 0.5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enemy := EnemyConversionInfo{Choices: map[string][]EntityChoice{"quake": test.choices}}
+			counts := countEntityChoices(&enemy, quake)
+			for name, share := range test.shares {
+				got := float64(counts[name]) / (128 * 128)
+				if math.Abs(got-share) > 0.02 {
+					t.Errorf("%s: expected a share of %.2f, got %.3f", name, share, got)
+				}
+			}
+		})
+	}
+}
+
+func TestEntityChoiceSeed(t *testing.T) {
+	restoreMappingTables(t)
+	quake, _ := GetTargetProfile("quake")
+	enemy := EnemyConversionInfo{Choices: map[string][]EntityChoice{
+		"quake": {{Name: "monster_army"}, {Name: "monster_enforcer"}, {Name: "monster_ogre"}},
+	}}
+
+	// no seed or weights, the pick only depends on the position
+	EntitySeed = nil
+	for _, actor := range []ActorInfo{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 5, Y: 6}} {
+		expected := enemy.Choices["quake"][(actor.X+actor.Y)%3].Name
+		if choice := enemy.EntityChoice(&actor, quake); choice.Name != expected {
+			t.Errorf("(%d,%d): expected %s, got %s", actor.X, actor.Y, expected, choice.Name)
+		}
+	}
+
+	// the same seed converts the same way
+	seed := int64(42)
+	EntitySeed = &seed
+	first := countEntityChoices(&enemy, quake)
+	picks := make([]string, 0, 128)
+	for x := 0; x < 128; x++ {
+		picks = append(picks, enemy.EntityChoice(&ActorInfo{X: x, Y: 7}, quake).Name)
+	}
+	second := countEntityChoices(&enemy, quake)
+	for name, count := range first {
+		if second[name] != count {
+			t.Errorf("%s: picked %d times, then %d times with the same seed", name, count, second[name])
+		}
+	}
+	for x, name := range picks {
+		if choice := enemy.EntityChoice(&ActorInfo{X: x, Y: 7}, quake); choice.Name != name {
+			t.Errorf("(%d,7): picked %s, then %s with the same seed", x, name, choice.Name)
+		}
+	}
+
+	// a different seed converts differently
+	otherSeed := int64(43)
+	EntitySeed = &otherSeed
+	changed := false
+	for x, name := range picks {
+		if enemy.EntityChoice(&ActorInfo{X: x, Y: 7}, quake).Name != name {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		t.Errorf("seeds 42 and 43 picked the same entities")
+	}
+}
+
+// the built-in keys of blades only go to the target they're meant for
+func TestBuiltInItemKeys(t *testing.T) {
+	tests := []struct {
+		target string
+		keys   map[string]string
+	}{
+		{"quake", nil},
+		{"dusk", map[string]string{"damage": "10.0", "frequency": "0.8"}},
+		{"ad", map[string]string{"dmg": "10"}},
+	}
+	item := Items[0xae]
+	for _, test := range tests {
+		target, _ := GetTargetProfile(test.target)
+		if keys := item.EntityKeys(target); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("%s: expected %v, got %v", test.target, test.keys, keys)
+		}
+	}
+}
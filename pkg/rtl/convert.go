@@ -5,6 +5,7 @@ import (
 	"gitlab.com/camtap/rott2quake/pkg/quakemap"
 	"log"
 	"math"
+	"strings"
 )
 
 // RTL to Quake MAP conversion functions
@@ -15,6 +16,11 @@ import (
 
 const (
 	PushWallTriggerMargin float64 = 0.15
+	SwitchButtonDepth     float64 = 4.0
+	WindowSealDepth       float64 = 8.0
+	GlassBreakMargin      float64 = 0.5
+	GlassBreakSpeed       float64 = 1000.0
+	GlassAlpha            float64 = 0.6
 	MovingObjectBaseSpeed         = 55.0
 	ElevatingGADBaseSpeed         = 150.0
 )
@@ -56,26 +62,13 @@ func ClassNameForMaskedWall(w *MaskedWallInfo, position string) string {
 	return "func_detail"
 }
 
-func SpawnClipEntity(x1, y1, z1, x2, y2, z2 float64, actor *ActorInfo, dusk bool, qm *quakemap.QuakeMap) *quakemap.Entity {
+func SpawnClipEntity(x1, y1, z1, x2, y2, z2 float64, actor *ActorInfo, target TargetProfile, qm *quakemap.QuakeMap) *quakemap.Entity {
 	clipBrush := quakemap.BasicCuboid(
 		x1, y1, z1,
 		x2, y2, z2,
 		"clip", 1.0, false)
-	clipEntity := qm.SpawnEntity("func_detail", 0)
-	if dusk {
-		// FIXME when clip textures are no longer busted in Dusk,
-		// see https://discord.com/channels/240195284695646209/586508960128040961/799031954728419348
-		clipEntity.ClassName = "func_wall"
-		clipEntity.AdditionalKeys["rendermode"] = "1"
-		clipEntity.AdditionalKeys["renderamt"] = "0"
-		clipBrush = quakemap.BasicCuboid(
-			x1, y1, z1,
-			x2, y2, z2,
-			// just pick an arbitrary texture besides "clip"
-			"FLRCL1", 1.0, false)
-	}
+	clipEntity := target.SpawnClip(qm, clipBrush)
 	AddDefaultEntityKeys(clipEntity, actor)
-	clipEntity.Brushes = []quakemap.Brush{clipBrush}
 	return clipEntity
 }
 
@@ -101,7 +94,7 @@ type ElevatorNode struct {
 // Adds func_button and trigger_teleport entities to link elevators
 func LinkElevators(rtlmap *RTLMapData, textureWad string,
 	floorDepth, gridSizeX, gridSizeY, gridSizeZ, scale float64,
-	dusk bool, qm *quakemap.QuakeMap) {
+	target TargetProfile, qm *quakemap.QuakeMap) {
 	elevators := make(map[uint16][]ElevatorNode)
 
 	elevatorSwitchTile := uint16(0x4c)
@@ -253,7 +246,7 @@ func LinkElevators(rtlmap *RTLMapData, textureWad string,
 	}
 }
 
-func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 	var floorDepth float64 = 64.0 * scale
@@ -273,14 +266,10 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 	entityKeys := make(map[string]string)
 
 	clipBrush := gadBrushes[len(gadBrushes)-1]
-	if dusk {
-		// HACK/FIXME: Dusk SDK ignores clip textures, so make them separate
-		// entities. Assume last brush is the surrounding clip texture.
-		clipBrush.SetTextureForAllPlanes("FLRCL1")
-		clipEntity := qm.SpawnEntity("func_wall", 0)
-		clipEntity.AdditionalKeys["rendermode"] = "1"
-		clipEntity.AdditionalKeys["renderamt"] = "0"
-		clipEntity.AddBrush(clipBrush)
+	if !target.ClipInEntities() {
+		// make the clip brush a separate entity. Assume last brush is
+		// the surrounding clip texture.
+		target.SpawnClip(qm, clipBrush)
 		gadBrushes = gadBrushes[0 : len(gadBrushes)-1]
 	}
 
@@ -305,7 +294,7 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 		lastPathCorner = initialCorner
 		qm.AddEntity(lastPathCorner)
 		entityKeys["target"] = initialCorner.AdditionalKeys["targetname"]
-		entityKeys["speed"] = fmt.Sprintf("%.02f", float64(moveInfo.Speed)*MovingObjectBaseSpeed*scale)
+		entityKeys["speed"] = fmt.Sprintf("%.02f", moveInfo.TrainSpeed(scale))
 
 		currentNode := gadPath
 		nodeToTargetNames := make(map[*PathNode]string)
@@ -351,6 +340,12 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 		qm.AddEntity(lowerPathEntity)
 	}
 
+	if trigger, touchplateX, touchplateY := rtlmap.ActorTrigger(actor, TRIGGER_GAD); trigger != nil {
+		// func_train waits to be triggered when it has a targetname
+		entityKeys["targetname"] = fmt.Sprintf("gad_%d_%d", actor.X, actor.Y)
+		SpawnTriggerRelay(qm, dX, dY, dZ, touchplateX, touchplateY, entityKeys["targetname"], trigger.Delay)
+	}
+
 	GADEntity := qm.SpawnEntity(entityClassname, 0)
 	GADEntity.Brushes = gadBrushes
 	AddDefaultEntityKeys(GADEntity, actor)
@@ -400,7 +395,7 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 				log.Printf("(%d,%d)->(%d,%d) stepZ1 = %.02f, stepZ2 = %.02f", actor.X, actor.Y, neighbor.X, neighbor.Y,
 					stepZ1, stepZ2)
 				_ = SpawnClipEntity(stepX1, stepY1, stepZ1, stepX2, stepY2, stepZ2,
-					actor, dusk, qm)
+					actor, target, qm)
 			}
 		}
 
@@ -416,7 +411,7 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 				stepX2 := stepX1 + GADEntity.Width()
 				stepZ1, stepZ2 := stepZCoords(neighborZOffset, zDiff)
 				_ = SpawnClipEntity(stepX1, stepY1, stepZ1, stepX2, stepY2, stepZ2,
-					actor, dusk, qm)
+					actor, target, qm)
 			}
 		}
 
@@ -432,7 +427,7 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 				stepY2 := stepY1 - GADEntity.Length()
 				stepZ1, stepZ2 := stepZCoords(neighborZOffset, zDiff)
 				_ = SpawnClipEntity(stepX1, stepY1, stepZ1, stepX2, stepY2, stepZ2,
-					actor, dusk, qm)
+					actor, target, qm)
 			}
 		}
 
@@ -448,7 +443,7 @@ func CreateGAD(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, q
 				stepX2 := stepX1 + GADEntity.Width()
 				stepZ1, stepZ2 := stepZCoords(neighborZOffset, zDiff)
 				_ = SpawnClipEntity(stepX1, stepY1, stepZ1, stepX2, stepY2, stepZ2,
-					actor, dusk, qm)
+					actor, target, qm)
 			}
 		}
 
@@ -473,7 +468,7 @@ func ClipHeight(rtlmap *RTLMapData, actor *ActorInfo, scale float64) float64 {
 	}
 }
 
-func AddThinWallClipTextures(rtlmap *RTLMapData, actor *ActorInfo, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+func AddThinWallClipTextures(rtlmap *RTLMapData, actor *ActorInfo, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 
@@ -492,7 +487,7 @@ func AddThinWallClipTextures(rtlmap *RTLMapData, actor *ActorInfo, scale float64
 				(float64(actor.X)+0.5)*gridSizeX,
 				float64(actor.Y+1)*-gridSizeY,
 				westClipZ-1,
-				actor, dusk, qm,
+				actor, target, qm,
 			)
 		}
 
@@ -506,7 +501,7 @@ func AddThinWallClipTextures(rtlmap *RTLMapData, actor *ActorInfo, scale float64
 				float64(actor.X+1)*gridSizeX,
 				float64(actor.Y+1)*-gridSizeY,
 				eastClipZ-1,
-				actor, dusk, qm,
+				actor, target, qm,
 			)
 		}
 	} else {
@@ -520,7 +515,7 @@ func AddThinWallClipTextures(rtlmap *RTLMapData, actor *ActorInfo, scale float64
 				float64(actor.X+1)*gridSizeX,
 				(float64(actor.Y)+0.5)*-gridSizeY,
 				northClipZ-1,
-				actor, dusk, qm,
+				actor, target, qm,
 			)
 		}
 
@@ -534,13 +529,13 @@ func AddThinWallClipTextures(rtlmap *RTLMapData, actor *ActorInfo, scale float64
 				float64(actor.X+1)*gridSizeX,
 				float64(actor.Y+1)*-gridSizeY,
 				southClipZ-1,
-				actor, dusk, qm,
+				actor, target, qm,
 			)
 		}
 	}
 }
 
-func CreateThinWall(rtlmap *RTLMapData, x, y int, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+func CreateThinWall(rtlmap *RTLMapData, x, y int, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var x1, y1, x2, y2 float64
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
@@ -620,7 +615,7 @@ func CreateThinWall(rtlmap *RTLMapData, x, y int, scale float64, dusk bool, qm *
 			qm.WorldSpawn.AddBrush(wallColumn)
 		}
 
-		AddThinWallClipTextures(rtlmap, &actor, scale, dusk, qm)
+		AddThinWallClipTextures(rtlmap, &actor, scale, target, qm)
 	}
 }
 
@@ -628,35 +623,72 @@ func CreateTrigger(rtlmap *RTLMapData, actor *ActorInfo, scale float64, qm *quak
 	switch actor.Type {
 	case WALL_MaskedWall:
 		// rendered in CreateMaskedWall
-	case WALL_Regular:
-		CreateWallSwitchTrigger(rtlmap, actor, scale, qm)
+	case WALL_Regular, WALL_Switch:
+		CreateWallSwitch(rtlmap, actor, scale, qm)
 	default:
 		CreateTouchplate(rtlmap, actor, scale, qm)
 	}
 }
 
-func CreateWallSwitchTrigger(rtlmap *RTLMapData, actor *ActorInfo, scale float64, qm *quakemap.QuakeMap) {
+// adds func_buttons on every exposed face of a switch wall, pressing
+// any of them fires the switch's relays
+func CreateWallSwitch(rtlmap *RTLMapData, actor *ActorInfo, scale float64, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 	var gridSizeZ float64 = 64.0 * scale
 	var floorDepth float64 = 64.0 * scale
 
+	buttonDepth := SwitchButtonDepth * scale
 	x1 := float64(actor.X) * gridSizeX
 	y1 := float64(actor.Y) * -gridSizeY
-	z1 := floorDepth
 	x2 := float64(actor.X+1) * gridSizeX
 	y2 := float64(actor.Y+1) * -gridSizeY
-	z2 := floorDepth + float64(rtlmap.FloorHeight())*gridSizeZ
+	z1 := floorDepth
+	z2 := floorDepth + gridSizeZ
 
-	// build column that overlaps the wall
-	wallColumnBrush := quakemap.BasicCuboid(x1, y1, z1,
-		x2, y2, z2,
-		"__TB_empty", scale, true)
-	triggerEntity := qm.SpawnEntity("trigger_multiple", 0)
-	triggerEntity.AdditionalKeys["target"] = fmt.Sprintf("trigger_%d_%d", actor.X, actor.Y)
-	triggerEntity.AdditionalKeys["message"] = "Switch Triggered."
-	triggerEntity.AddBrush(wallColumnBrush)
-	AddDefaultEntityKeys(triggerEntity, actor)
+	// stays pressed unless the switch toggles something
+	wait := "-1"
+	for _, trigger := range actor.MapTriggers {
+		if trigger.Action.Repeatable() {
+			wait = fmt.Sprintf("%.02f", TouchplateRetriggerWait)
+		}
+	}
+
+	for _, direction := range []WallDirection{DIR_East, DIR_North, DIR_West, DIR_South} {
+		deltaX, deltaY := direction.Delta()
+		neighborX, neighborY := actor.X+deltaX, actor.Y+deltaY
+		if neighborX < 0 || neighborX > 127 || neighborY < 0 || neighborY > 127 ||
+			rtlmap.ActorGrid[neighborY][neighborX].IsWall() {
+			continue
+		}
+
+		// button sits on the face and gets pushed into the wall
+		var bx1, by1, bx2, by2 float64
+		var angle string
+		switch direction {
+		case DIR_East:
+			bx1, by1, bx2, by2 = x2, y1, x2+buttonDepth, y2
+			angle = "180"
+		case DIR_North:
+			bx1, by1, bx2, by2 = x1, y1+buttonDepth, x2, y1
+			angle = "270"
+		case DIR_West:
+			bx1, by1, bx2, by2 = x1-buttonDepth, y1, x1, y2
+			angle = "0"
+		case DIR_South:
+			bx1, by1, bx2, by2 = x1, y2, x2, y2-buttonDepth
+			angle = "90"
+		}
+
+		buttonEntity := qm.SpawnEntity("func_button", 0)
+		buttonEntity.AddBrush(quakemap.BasicCuboid(bx1, by1, z1, bx2, by2, z2,
+			actor.SwitchTextureName(), scale, false))
+		buttonEntity.AdditionalKeys["target"] = TouchplateTargetName(actor.X, actor.Y)
+		buttonEntity.AdditionalKeys["angle"] = angle
+		buttonEntity.AdditionalKeys["lip"] = fmt.Sprintf("%.02f", scale)
+		buttonEntity.AdditionalKeys["wait"] = wait
+		AddDefaultEntityKeys(buttonEntity, actor)
+	}
 }
 
 func CreateTouchplate(rtlmap *RTLMapData, actor *ActorInfo, scale float64, qm *quakemap.QuakeMap) {
@@ -665,11 +697,34 @@ func CreateTouchplate(rtlmap *RTLMapData, actor *ActorInfo, scale float64, qm *q
 	var gridSizeZ float64 = 64.0 * scale
 	var floorDepth float64 = 64.0 * scale
 
-	relayTargetName := fmt.Sprintf("trigger_%d_%d", actor.X, actor.Y)
+	relayTargetName := TouchplateTargetName(actor.X, actor.Y)
 
-	triggerEntity := qm.SpawnEntity("trigger_once", 0)
+	// touchplates that toggle something can be stepped on again
+	triggerClassName := "trigger_once"
+	for _, trigger := range actor.MapTriggers {
+		if trigger.Action.Repeatable() {
+			triggerClassName = "trigger_multiple"
+		}
+		if trigger.Action == TRIGGER_LightToggle {
+			// lights aren't entities of their own in ROTT, so the
+			// switchable light is spawned along with its touchplate
+			lightName := fmt.Sprintf("light_%d_%d", trigger.Actor.X, trigger.Actor.Y)
+			lightEntity := qm.SpawnEntity("light", 0)
+			lightEntity.OriginX = (float64(trigger.Actor.X) + 0.5) * gridSizeX
+			lightEntity.OriginY = (float64(trigger.Actor.Y) + 0.5) * -gridSizeY
+			lightEntity.OriginZ = floorDepth + gridSizeZ + (8.0 * scale)
+			lightEntity.AdditionalKeys["targetname"] = lightName
+			lightEntity.AdditionalKeys["light"] = "200"
+			SpawnTriggerRelay(qm, lightEntity.OriginX, lightEntity.OriginY, lightEntity.OriginZ,
+				actor.X, actor.Y, lightName, trigger.Delay)
+		}
+	}
+
+	triggerEntity := qm.SpawnEntity(triggerClassName, 0)
 	triggerEntity.AdditionalKeys["target"] = relayTargetName
-	triggerEntity.AdditionalKeys["message"] = "Touchplate Triggered"
+	if triggerClassName == "trigger_multiple" {
+		triggerEntity.AdditionalKeys["wait"] = fmt.Sprintf("%.02f", TouchplateRetriggerWait)
+	}
 	triggerEntity.AddBrush(
 		quakemap.BasicCuboid(float64(actor.X)*gridSizeX, float64(actor.Y)*-gridSizeY, floorDepth,
 			float64(actor.X+1)*gridSizeX, float64(actor.Y+1)*-gridSizeY, floorDepth+gridSizeZ,
@@ -703,6 +758,55 @@ func CreateSingleUnitWall(rtlmap *RTLMapData, x, y int, scale float64, textureNa
 	qm.WorldSpawn.AddBrush(wallColumn)
 }
 
+// windows are rendered as a see-through masked column with a clip
+// brush blocking the player. Neither seals the map, so faces looking
+// into the void get a world brush behind them to keep it from leaking.
+func CreateWindow(rtlmap *RTLMapData, x, y int, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+	var gridSizeZ float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+
+	actor := &rtlmap.ActorGrid[y][x]
+	x1 := float64(x) * gridSizeX
+	y1 := float64(y) * -gridSizeY
+	z1 := floorDepth
+	x2 := float64(x+1) * gridSizeX
+	y2 := float64(y+1) * -gridSizeY
+	z2 := floorDepth + float64(rtlmap.FloorHeight())*gridSizeZ
+
+	windowEntity := qm.SpawnEntity("func_illusionary", 0)
+	windowEntity.AddBrush(quakemap.BasicCuboid(x1, y1, z1, x2, y2, z2,
+		"{"+actor.WallTileToTextureName(false), scale, true))
+	AddDefaultEntityKeys(windowEntity, actor)
+
+	_ = SpawnClipEntity(x1, y1, z1, x2, y2, z2, actor, target, qm)
+
+	sealDepth := WindowSealDepth * scale
+	for _, direction := range []WallDirection{DIR_East, DIR_North, DIR_West, DIR_South} {
+		deltaX, deltaY := direction.Delta()
+		neighborX, neighborY := x+deltaX, y+deltaY
+		if neighborX >= 0 && neighborX <= 127 && neighborY >= 0 && neighborY <= 127 &&
+			(rtlmap.ActorGrid[neighborY][neighborX].IsWall() || rtlmap.IsFloor(neighborX, neighborY)) {
+			continue
+		}
+
+		var sx1, sy1, sx2, sy2 float64
+		switch direction {
+		case DIR_East:
+			sx1, sy1, sx2, sy2 = x2, y1, x2+sealDepth, y2
+		case DIR_North:
+			sx1, sy1, sx2, sy2 = x1, y1+sealDepth, x2, y1
+		case DIR_West:
+			sx1, sy1, sx2, sy2 = x1-sealDepth, y1, x1, y2
+		case DIR_South:
+			sx1, sy1, sx2, sy2 = x1, y2, x2, y2-sealDepth
+		}
+		qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(sx1, sy1, z1, sx2, sy2, z2,
+			actor.WallTileToTextureName(false), scale, false))
+	}
+}
+
 func CreateRegularWall(rtlmap *RTLMapData, x, y int, scale float64, qm *quakemap.QuakeMap) {
 	switch rtlmap.WallPlane[y][x] {
 	case 0x2f:
@@ -743,10 +847,26 @@ func CreateRegularWallSingleTexture(rtlmap *RTLMapData, x, y int, scale float64,
 	y2 := float64(y+1) * -gridSizeY
 	z2 := floorDepth + float64(rtlmap.FloorHeight())*gridSizeZ
 
-	// plain ol' column
-	wallColumn = quakemap.BasicCuboid(x1, y1, z1,
+	// plain ol' column, with door frames on faces next to doors and
+	// masked walls
+	cuboidParams := quakemap.BasicCuboidParams(texName, scale, true)
+	if actor.MapFlags&WALLFLAGS_Moving == 0 {
+		if jambTexture := rtlmap.JambTexture(x, y, DIR_North); jambTexture != "" {
+			cuboidParams.North.Texture = jambTexture
+		}
+		if jambTexture := rtlmap.JambTexture(x, y, DIR_South); jambTexture != "" {
+			cuboidParams.South.Texture = jambTexture
+		}
+		if jambTexture := rtlmap.JambTexture(x, y, DIR_East); jambTexture != "" {
+			cuboidParams.East.Texture = jambTexture
+		}
+		if jambTexture := rtlmap.JambTexture(x, y, DIR_West); jambTexture != "" {
+			cuboidParams.West.Texture = jambTexture
+		}
+	}
+	wallColumn = quakemap.BuildCuboidBrush(x1, y1, z1,
 		x2, y2, z2,
-		texName, scale, true)
+		cuboidParams)
 
 	if actor.MapFlags&WALLFLAGS_Moving != 0 {
 		var lastPathCorner, currentPathCorner *quakemap.Entity
@@ -781,8 +901,7 @@ func CreateRegularWallSingleTexture(rtlmap *RTLMapData, x, y int, scale float64,
 		if pathType == PATH_Perpetual {
 			currentPathCorner.AdditionalKeys["target"] = nodeToTargetNames[currentNode]
 		} else {
-			lastPathCorner.AdditionalKeys["target"] = "idontexist"
-			lastPathCorner.AdditionalKeys["wait"] = "-1"
+			StopTrainAtCorner(lastPathCorner)
 		}
 
 		entityType = "func_train"
@@ -813,53 +932,61 @@ func CreateRegularWallSingleTexture(rtlmap *RTLMapData, x, y int, scale float64,
 				triggerY := int(infoVal) & 0xff
 
 				// create trigger_relay to match the touchplate/switch
-				relayEntity := qm.SpawnEntity("trigger_relay", 0)
-				relayEntity.OriginX = (float64(actor.X) + 0.5) * gridSizeX
-				relayEntity.OriginY = (float64(actor.Y) + 0.5) * -gridSizeY
-				relayEntity.OriginZ = floorDepth + (float64(rtlmap.FloorHeight()+1))*gridSizeZ
-				relayEntity.AdditionalKeys["targetname"] = fmt.Sprintf("trigger_%d_%d", triggerX, triggerY)
-				relayEntity.AdditionalKeys["target"] = wallTargetName
+				SpawnTriggerRelay(qm,
+					(float64(actor.X)+0.5)*gridSizeX,
+					(float64(actor.Y)+0.5)*-gridSizeY,
+					floorDepth+(float64(rtlmap.FloorHeight()+1))*gridSizeZ,
+					triggerX, triggerY, wallTargetName, rtlmap.TouchplateDelay(triggerX, triggerY))
 			} else if spriteVal < 256 {
-				var tx1, ty1, tx2, ty2 float64
-
-				// only allow pushing from the opposite direction it
-				// moves toward when triggered
-				switch actor.SpriteValue {
-				case 300, uint16(DIR_East) + uint16(ICONARROWS):
-					tx1 = (float64(actor.X) * gridSizeX) - 1
-					tx2 = tx1 + 1.0
-					ty1 = (float64(actor.Y) + PushWallTriggerMargin) * -gridSizeY
-					ty2 = (float64(actor.Y+1) - PushWallTriggerMargin) * -gridSizeY
-				case 318, uint16(DIR_North) + uint16(ICONARROWS):
-					tx1 = (float64(actor.X) + PushWallTriggerMargin) * gridSizeX
-					tx2 = (float64(actor.X+1) - PushWallTriggerMargin) * gridSizeX
-					ty1 = (float64(actor.Y+1) * -gridSizeY) + 1
-					ty2 = ty1 - 1.0
-				case 336, uint16(DIR_West) + uint16(ICONARROWS):
-					tx1 = (float64(actor.X+1) * gridSizeX) - 1
-					tx2 = tx1 + 1.0
-					ty1 = (float64(actor.Y) + PushWallTriggerMargin) * -gridSizeY
-					ty2 = (float64(actor.Y+1) - PushWallTriggerMargin) * -gridSizeY
-				case 354, uint16(DIR_South) + uint16(ICONARROWS):
-					tx1 = (float64(actor.X) + PushWallTriggerMargin) * gridSizeX
-					tx2 = (float64(actor.X+1) - PushWallTriggerMargin) * gridSizeX
-					ty1 = (float64(actor.Y) * -gridSizeY) + 1
-					ty2 = ty1 - 1.0
-				default:
-					panic("yes you're stuck implementing diagonal pushwall triggers")
+				// only allow pushing from the side(s) opposite to the
+				// direction it moves toward when triggered
+				pushDeltaX, pushDeltaY := moveWallInfo.InitialDirection.Delta()
+				var triggerBrushes []quakemap.Brush
+				switch {
+				case pushDeltaX > 0:
+					// west face
+					tx1 := (float64(actor.X) * gridSizeX) - 1
+					ty1 := (float64(actor.Y) + PushWallTriggerMargin) * -gridSizeY
+					ty2 := (float64(actor.Y+1) - PushWallTriggerMargin) * -gridSizeY
+					triggerBrushes = append(triggerBrushes, quakemap.BasicCuboid(tx1, ty1, z1, tx1+1.0, ty2, z2, "trigger", scale, true))
+				case pushDeltaX < 0:
+					// east face
+					tx1 := float64(actor.X+1) * gridSizeX
+					ty1 := (float64(actor.Y) + PushWallTriggerMargin) * -gridSizeY
+					ty2 := (float64(actor.Y+1) - PushWallTriggerMargin) * -gridSizeY
+					triggerBrushes = append(triggerBrushes, quakemap.BasicCuboid(tx1, ty1, z1, tx1+1.0, ty2, z2, "trigger", scale, true))
 				}
-				// add pushwall trigger_once entity within the wall
-				pushWallTriggerEntity := qm.SpawnEntity("trigger_once", 0)
-				pushWallTriggerEntity.AddBrush(
-					quakemap.BasicCuboid(tx1, ty1, z1, tx2, ty2, z2, "trigger", scale, true),
-				)
-				pushWallTriggerEntity.AdditionalKeys["_x"] = fmt.Sprintf("%d", actor.X)
-				pushWallTriggerEntity.AdditionalKeys["_y"] = fmt.Sprintf("%d", actor.Y)
-				pushWallTriggerEntity.AdditionalKeys["target"] = wallTargetName
-				pushWallTriggerEntity.AdditionalKeys["targetname"] = fmt.Sprintf("movewallpath_%d_%d_push", actor.X, actor.Y)
-				entity.AdditionalKeys["targetname"] = fmt.Sprintf("movewallpath_%d_%d_wall", actor.X, actor.Y)
+				switch {
+				case pushDeltaY < 0:
+					// south face
+					tx1 := (float64(actor.X) + PushWallTriggerMargin) * gridSizeX
+					tx2 := (float64(actor.X+1) - PushWallTriggerMargin) * gridSizeX
+					ty1 := float64(actor.Y+1) * -gridSizeY
+					triggerBrushes = append(triggerBrushes, quakemap.BasicCuboid(tx1, ty1, z1, tx2, ty1-1.0, z2, "trigger", scale, true))
+				case pushDeltaY > 0:
+					// north face
+					tx1 := (float64(actor.X) + PushWallTriggerMargin) * gridSizeX
+					tx2 := (float64(actor.X+1) - PushWallTriggerMargin) * gridSizeX
+					ty1 := (float64(actor.Y) * -gridSizeY) + 1
+					triggerBrushes = append(triggerBrushes, quakemap.BasicCuboid(tx1, ty1, z1, tx2, ty1-1.0, z2, "trigger", scale, true))
+				}
+				// add pushwall trigger_once entities next to the wall,
+				// diagonal pushwalls can be pushed from either side
+				for _, triggerBrush := range triggerBrushes {
+					pushWallTriggerEntity := qm.SpawnEntity("trigger_once", 0)
+					pushWallTriggerEntity.AddBrush(triggerBrush)
+					pushWallTriggerEntity.AdditionalKeys["_x"] = fmt.Sprintf("%d", actor.X)
+					pushWallTriggerEntity.AdditionalKeys["_y"] = fmt.Sprintf("%d", actor.Y)
+					pushWallTriggerEntity.AdditionalKeys["target"] = wallTargetName
+					pushWallTriggerEntity.AdditionalKeys["targetname"] = fmt.Sprintf("movewallpath_%d_%d_push", actor.X, actor.Y)
+				}
+				entity.AdditionalKeys["targetname"] = wallTargetName
+			}
+			entity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", moveWallInfo.TrainSpeed(scale))
+			if spriteVal >= 256 {
+				// movewalls keep going regardless of what's in their way
+				entity.AdditionalKeys["dmg"] = fmt.Sprintf("%d", MoveWallDamage)
 			}
-			entity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", float64(moveWallInfo.Speed)*MovingObjectBaseSpeed*scale)
 		}
 
 		if entityType == "func_wall" && actor.Damage {
@@ -875,6 +1002,14 @@ func CreateRegularWallSingleTexture(rtlmap *RTLMapData, x, y int, scale float64,
 	}
 }
 
+// makes a func_train come to a halt once it reaches the path_corner.
+// id1's func_train does not honor "wait" "-1" and removes itself when
+// a path_corner has no target, so park it on the corner instead.
+func StopTrainAtCorner(corner *quakemap.Entity) {
+	corner.AdditionalKeys["target"] = corner.AdditionalKeys["targetname"]
+	corner.AdditionalKeys["wait"] = "999999"
+}
+
 func CreatePlatform(rtlmap *RTLMapData, x, y int, scale float64, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
@@ -934,7 +1069,7 @@ func CreatePlatform(rtlmap *RTLMapData, x, y int, scale float64, qm *quakemap.Qu
 	}
 }
 
-func CreateMaskedWall(rtlmap *RTLMapData, x, y int, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+func CreateMaskedWall(rtlmap *RTLMapData, x, y int, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 	var gridSizeZ float64 = 64.0 * scale
@@ -1012,7 +1147,11 @@ func CreateMaskedWall(rtlmap *RTLMapData, x, y int, scale float64, dusk bool, qm
 			var abovez1 float64 = floorDepth + float64(rtlmap.FloorHeight()-1)*gridSizeZ
 			var abovez2 float64 = floorDepth + float64(rtlmap.FloorHeight())*gridSizeZ
 			aboveClassName := ClassNameForMaskedWall(&maskedWallInfo, "above")
-			cuboidParams := quakemap.BasicCuboidParams("{"+maskedWallInfo.Above, scale, false)
+			aboveTexture := "{" + maskedWallInfo.Above
+			if maskedWallInfo.IsSwitch {
+				aboveTexture = SwitchTextureName(maskedWallInfo.Above)
+			}
+			cuboidParams := quakemap.BasicCuboidParams(aboveTexture, scale, false)
 			cuboidParams.North.TexScaleX *= xScaleFactor
 			cuboidParams.South.TexScaleX *= xScaleFactor
 			cuboidParams.East.TexScaleX *= xScaleFactor
@@ -1054,22 +1193,66 @@ func CreateMaskedWall(rtlmap *RTLMapData, x, y int, scale float64, dusk bool, qm
 			cuboidParams.South.TexScaleX *= xScaleFactor
 			cuboidParams.East.TexScaleX *= xScaleFactor
 			cuboidParams.West.TexScaleX *= xScaleFactor
+			if className == "func_breakable" && !target.HasBreakables() {
+				// stock Quake has no func_breakable, use a door
+				// that drops into the floor when shot instead
+				className = "func_door"
+				if wallDirection == WALLDIR_NorthSouth {
+					x1 -= GlassBreakMargin
+					x2 += GlassBreakMargin
+				} else {
+					y1 -= GlassBreakMargin
+					y2 += GlassBreakMargin
+				}
+			}
 			column := quakemap.BuildCuboidBrush(x1, y1, z1, x2, y2, z2, cuboidParams)
 			bottomEntity := qm.SpawnEntity(className, 0)
 			bottomEntity.AddBrush(column)
 			AddDefaultEntityKeys(bottomEntity, &wallInfo)
+			if className == "func_breakable" {
+				target.SetupBreakable(bottomEntity)
+			}
+			if className == "func_door" {
+				bottomEntity.AdditionalKeys["health"] = "1"
+				bottomEntity.AdditionalKeys["angle"] = "-2"
+				bottomEntity.AdditionalKeys["lip"] = "0"
+				bottomEntity.AdditionalKeys["wait"] = "-1"
+				bottomEntity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", GlassBreakSpeed*scale)
+				// translucent glass for engines that support it
+				bottomEntity.AdditionalKeys["alpha"] = fmt.Sprintf("%.02f", GlassAlpha)
+
+				// the shot out version sits slightly behind the
+				// glass and shows once the glass drops
+				if shotOutID, ok := ShotOutMaskedWalls[wallInfo.Tile]; ok {
+					if wallDirection == WALLDIR_NorthSouth {
+						x1 += GlassBreakMargin
+						x2 -= GlassBreakMargin
+					} else {
+						y1 += GlassBreakMargin
+						y2 -= GlassBreakMargin
+					}
+					shotOutParams := quakemap.BasicCuboidParams("{"+MaskedWalls[shotOutID].Bottom, scale, false)
+					shotOutParams.North.TexScaleX *= xScaleFactor
+					shotOutParams.South.TexScaleX *= xScaleFactor
+					shotOutParams.East.TexScaleX *= xScaleFactor
+					shotOutParams.West.TexScaleX *= xScaleFactor
+					shotOutEntity := qm.SpawnEntity("func_illusionary", 0)
+					shotOutEntity.AddBrush(quakemap.BuildCuboidBrush(x1, y1, z1, x2, y2, z2, shotOutParams))
+					AddDefaultEntityKeys(shotOutEntity, &wallInfo)
+				}
+			}
 		}
 
-		// TODO: sides
+		// sides are drawn on the walls framing it, see JambTexture
 
-		AddThinWallClipTextures(rtlmap, &wallInfo, scale, dusk, qm)
+		AddThinWallClipTextures(rtlmap, &wallInfo, scale, target, qm)
 
 	} else {
 		panic(fmt.Sprintf("Masked wall at %d,%d has non-existent ID (%d)", x, y, wallInfo.MaskedWallID))
 	}
 }
 
-func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+func CreateDoorEntities(rtlmap *RTLMapData, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 	var gridSizeZ float64 = 64.0 * scale
@@ -1077,17 +1260,20 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 
 	// determine which keys to use
 	keyCount := 0
-	availKeys := []string{"item_key1", "item_key2"}
-	if dusk {
-		availKeys = []string{"key_red_key", "key_blue_key", "key_yellow_key"}
-	}
+	availKeys := target.KeyEntityNames()
 	keyMap := make(map[DoorLock]int)
 
+	triggeredKeys := rtlmap.KeyStrategy == KEYS_Triggered
+	if rtlmap.KeyStrategy == KEYS_Auto && rtlmap.KeyedLockCount() > len(availKeys) {
+		log.Printf("Map uses %d keys but only %d are available, using triggered keys",
+			rtlmap.KeyedLockCount(), len(availKeys))
+		triggeredKeys = true
+	}
+
 	var timedTriggerEntity *quakemap.Entity
 
-	for doornum, door := range rtlmap.GetDoors() {
+	for doornum, door := range rtlmap.Doors {
 		doorEntity := qm.SpawnEntity("func_door", 0)
-		timeBeforeOpen := 0
 		flipTextures := false
 		if door.Direction == WALLDIR_NorthSouth {
 			if door.Tiles[0].Y > door.Tiles[len(door.Tiles)-1].Y &&
@@ -1111,9 +1297,6 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 				panic(fmt.Sprintf("(%d,%d) not WALL_Door type!", doorTile.X, doorTile.Y))
 			}
 			texInfo := GetDoorTextures(doorTile.Tile)
-			if doorTile.InfoValue > 0 {
-				timeBeforeOpen = int(doorTile.InfoValue>>8) * 60
-			}
 			var x1, y1, x2, y2, abovex1, abovey1, abovex2, abovey2 float64
 			var z1 float64 = floorDepth
 			var z2 float64 = floorDepth + gridSizeZ
@@ -1140,10 +1323,7 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 			}
 			cuboidParams := quakemap.BasicCuboidParams(texInfo.BaseTexture, scale, false)
 			if flipTextures {
-				cuboidParams.North.TexScaleX *= -1.0
-				cuboidParams.South.TexScaleX *= -1.0
-				cuboidParams.East.TexScaleX *= -1.0
-				cuboidParams.West.TexScaleX *= -1.0
+				cuboidParams.FlipSides()
 			}
 			doorEntity.AddBrush(
 				quakemap.BuildCuboidBrush(x1, y1, z1, x2, y2, z2, cuboidParams),
@@ -1159,12 +1339,18 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 		doorEntity.AdditionalKeys["_r2q_grid_start_x"] = fmt.Sprintf("%d", door.Tiles[0].X)
 		doorEntity.AdditionalKeys["_r2q_grid_start_y"] = fmt.Sprintf("%d", door.Tiles[0].Y)
 
+		// set before locking the door, locks may need it to stay open
+		behavior := door.Behavior()
+		doorEntity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", behavior.Speed*scale)
+		doorEntity.AdditionalKeys["wait"] = fmt.Sprintf("%.02f", behavior.Wait)
+		doorEntity.AdditionalKeys["sounds"] = fmt.Sprintf("%d", behavior.Sounds)
+
 		if door.Lock != LOCK_Unlocked && door.Lock != LOCK_Trigger {
 			if _, ok := keyMap[door.Lock]; !ok {
 				// place keys on the map
 				keyToUse := keyCount % len(availKeys)
 				log.Printf("Using key entity %s as %s", availKeys[keyToUse], door.Lock.KeyName())
-				if keyCount == len(availKeys) {
+				if keyCount == len(availKeys) && !triggeredKeys {
 					log.Printf("More than %d keys used, this map may not be playable (or fun)", len(availKeys))
 				}
 
@@ -1174,12 +1360,9 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 							entity := qm.SpawnEntity(availKeys[keyToUse], 0)
 							entity.OriginX = float64(x)*gridSizeX + (gridSizeX / 2)
 							entity.OriginY = float64(y)*-gridSizeY - (gridSizeY / 2.0)
-							entity.OriginZ = floorDepth + (gridSizeZ / 2)
-							if dusk {
-								// FIXME when/if dusk SDK fixes keys
-								// being placed a lot lower than the
-								// entity's origin
-								entity.OriginZ += 600.0
+							entity.OriginZ = floorDepth + (gridSizeZ / 2) + target.KeyZOffset()
+							if triggeredKeys {
+								CreateTriggeredKeyPickup(door.Lock, x, y, entity, scale, qm)
 							}
 						}
 					}
@@ -1189,10 +1372,10 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 				keyCount += 1
 			}
 
-			if dusk {
-				doorEntity.AdditionalKeys["key"] = fmt.Sprintf("%d", keyMap[door.Lock]+1)
+			if triggeredKeys {
+				CreateTriggeredKeyLock(rtlmap, &door, doorEntity, scale, qm)
 			} else {
-				doorEntity.SpawnFlags |= (2 - (keyMap[door.Lock])) * 8
+				target.LockDoor(doorEntity, keyMap[door.Lock])
 			}
 		}
 
@@ -1215,17 +1398,28 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 		} else {
 			doorEntity.AdditionalKeys["angle"] = "-1"
 		}
-		doorEntity.AdditionalKeys["speed"] = "290.0"
 
-		if timeBeforeOpen > 0 {
+		if triggeredKeys && door.Lock != LOCK_Unlocked && door.Lock != LOCK_Trigger {
+			// cannot be opened by touch again once closed
+			doorEntity.AdditionalKeys["wait"] = "-1"
+		} else if door.Lock == LOCK_Trigger {
+			// only opens when its touchplate is stepped on, and stays
+			// open since the touchplate only fires once
+			entityName := fmt.Sprintf("door_%d_%d", door.Tiles[0].X, door.Tiles[0].Y)
+			doorEntity.AdditionalKeys["targetname"] = entityName
+			SpawnTriggerRelay(qm,
+				float64(door.Tiles[0].X)*gridSizeX+(gridSizeX/2),
+				float64(door.Tiles[0].Y)*-gridSizeY-(gridSizeY/2.0),
+				floorDepth+(gridSizeZ/2),
+				door.TriggerX, door.TriggerY, entityName, rtlmap.TouchplateDelay(door.TriggerX, door.TriggerY))
+		} else if door.OpenDelay > 0 {
 			// timed door, only open after a delayed trigger
 			entityName := fmt.Sprintf("door_%d_%d", door.Tiles[0].X, door.Tiles[0].Y)
 			doorEntity.AdditionalKeys["targetname"] = entityName
-			doorEntity.AdditionalKeys["wait"] = "-1"
 			triggerEntity := qm.SpawnEntity("trigger_relay", 0)
 			triggerEntity.AdditionalKeys["target"] = entityName
 			triggerEntity.AdditionalKeys["targetname"] = "timed_delay_trigger"
-			triggerEntity.AdditionalKeys["delay"] = fmt.Sprintf("%d", timeBeforeOpen)
+			triggerEntity.AdditionalKeys["delay"] = fmt.Sprintf("%d", door.OpenDelay)
 			triggerEntity.AdditionalKeys["message"] = "Time-delay door opens."
 			triggerEntity.OriginX = float64(door.Tiles[0].X)*gridSizeX + (gridSizeX / 2)
 			triggerEntity.OriginY = float64(door.Tiles[0].Y)*-gridSizeY - (gridSizeY / 2.0)
@@ -1244,14 +1438,117 @@ func CreateDoorEntities(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakem
 	}
 }
 
-func AddExitPoints(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+// Turns the key item into a triggered key. Keys share the target's key
+// items once there are more ROTT keys than target keys, and items the
+// player already holds cannot be picked up again, so a trigger_once
+// over the key does the pickup instead and removes the key items of
+// the lock through a relay (id1 skips the targets of anything with a
+// killtarget).
+func CreateTriggeredKeyPickup(lock DoorLock, x, y int, keyEntity *quakemap.Entity, scale float64, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+	var gridSizeZ float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+
+	keyItemName := fmt.Sprintf("%s_item", lock.KeyTargetName())
+	firstPickup := true
+	for _, entity := range qm.Entities {
+		if entity.AdditionalKeys["targetname"] == keyItemName {
+			firstPickup = false
+		}
+	}
+	keyEntity.AdditionalKeys["targetname"] = keyItemName
+	if firstPickup {
+		relayEntity := qm.SpawnEntity("trigger_relay", 0)
+		relayEntity.OriginX = keyEntity.OriginX
+		relayEntity.OriginY = keyEntity.OriginY
+		relayEntity.OriginZ = keyEntity.OriginZ
+		relayEntity.AdditionalKeys["targetname"] = lock.KeyTargetName()
+		relayEntity.AdditionalKeys["killtarget"] = keyItemName
+	}
+
+	pickupEntity := qm.SpawnEntity("trigger_once", 0)
+	pickupEntity.AdditionalKeys["target"] = lock.KeyTargetName()
+	pickupEntity.AdditionalKeys["message"] = fmt.Sprintf("You got the %s key.", lock.KeyName())
+	pickupEntity.AddBrush(quakemap.BasicCuboid(
+		float64(x)*gridSizeX, float64(y)*-gridSizeY, floorDepth,
+		float64(x+1)*gridSizeX, float64(y+1)*-gridSizeY, floorDepth+gridSizeZ,
+		"trigger", scale, false,
+	))
+}
+
+// Locks a door until its key has been picked up: a trigger_counter
+// opens the door once it has been fired by both the key pickup and
+// the player touching the door, so picking up the key after trying
+// the door opens it straight away.
+func CreateTriggeredKeyLock(rtlmap *RTLMapData, door *Door, doorEntity *quakemap.Entity, scale float64, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+	var gridSizeZ float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+
+	doorName := fmt.Sprintf("door_%d_%d", door.Tiles[0].X, door.Tiles[0].Y)
+	counterName := fmt.Sprintf("keylock_%d_%d", door.Tiles[0].X, door.Tiles[0].Y)
+	doorEntity.AdditionalKeys["targetname"] = doorName
+
+	originX := float64(door.Tiles[0].X)*gridSizeX + (gridSizeX / 2)
+	originY := float64(door.Tiles[0].Y)*-gridSizeY - (gridSizeY / 2.0)
+	originZ := floorDepth + (gridSizeZ / 2)
+
+	counterEntity := qm.SpawnEntity("trigger_counter", 0)
+	counterEntity.OriginX = originX
+	counterEntity.OriginY = originY
+	counterEntity.OriginZ = originZ
+	counterEntity.SpawnFlags = 1 // no "n more to go" messages
+	counterEntity.AdditionalKeys["targetname"] = counterName
+	counterEntity.AdditionalKeys["target"] = doorName
+	counterEntity.AdditionalKeys["count"] = "2"
+
+	relayEntity := qm.SpawnEntity("trigger_relay", 0)
+	relayEntity.OriginX = originX
+	relayEntity.OriginY = originY
+	relayEntity.OriginZ = originZ
+	relayEntity.AdditionalKeys["targetname"] = door.Lock.KeyTargetName()
+	relayEntity.AdditionalKeys["target"] = counterName
+
+	// covers the door tiles so that walking up to either side counts
+	minX, minY := door.Tiles[0].X, door.Tiles[0].Y
+	maxX, maxY := minX, minY
+	for _, tile := range door.Tiles {
+		if tile.X < minX {
+			minX = tile.X
+		}
+		if tile.Y < minY {
+			minY = tile.Y
+		}
+		if tile.X > maxX {
+			maxX = tile.X
+		}
+		if tile.Y > maxY {
+			maxY = tile.Y
+		}
+	}
+	// the lock message only shows on the first touch, a message on the
+	// door itself would keep showing once unlocked
+	touchEntity := qm.SpawnEntity("trigger_once", 0)
+	touchEntity.AdditionalKeys["target"] = counterName
+	touchEntity.AdditionalKeys["message"] = door.Lock.Message()
+	touchEntity.AddBrush(quakemap.BasicCuboid(
+		float64(minX)*gridSizeX, float64(minY)*-gridSizeY, floorDepth,
+		float64(maxX+1)*gridSizeX, float64(maxY+1)*-gridSizeY, floorDepth+gridSizeZ,
+		"trigger", scale, false,
+	))
+}
+
+func AddExitPoints(rtlmap *RTLMapData, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 	var gridSizeZ float64 = 64.0 * scale
 	var floorDepth float64 = 64.0 * scale
 
 	for _, point := range rtlmap.ExitPoints {
-		log.Printf("Adding map%03d exit point at (%d,%d)", point.DestMap, point.X, point.Y)
+		destination := rtlmap.ExitDestination(point)
+		log.Printf("Adding %s exit point at (%d,%d)", destination, point.X, point.Y)
 		brush := quakemap.BasicCuboid(
 			(float64(point.X)+0.25)*gridSizeX,
 			(float64(point.Y)+0.25)*-gridSizeY,
@@ -1261,12 +1558,73 @@ func AddExitPoints(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.Qu
 			floorDepth+gridSizeZ,
 			"trigger", scale, false)
 		entity := qm.SpawnEntity("trigger_changelevel", 0)
-		entity.AdditionalKeys["map"] = fmt.Sprintf("map%03d", point.DestMap)
+		entity.AdditionalKeys["map"] = destination
 		entity.AddBrush(brush)
 	}
 }
 
-func AddEnemies(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.QuakeMap) {
+// Adds a trigger_secret covering each secret area, with a brush for
+// every run of secret tiles along a row
+func AddSecretAreas(rtlmap *RTLMapData, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+	var gridSizeZ float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+
+	for _, area := range rtlmap.SecretAreas {
+		log.Printf("Adding secret area at (%d,%d) (%d tiles)", area.Tiles[0].X, area.Tiles[0].Y, len(area.Tiles))
+		entity := qm.SpawnEntity("trigger_secret", 0)
+		if area.SecretExit {
+			entity.AdditionalKeys["message"] = "You found a secret exit!"
+		} else {
+			entity.AdditionalKeys["message"] = "You found a secret area!"
+		}
+
+		var inArea [128][128]bool
+		for _, tile := range area.Tiles {
+			inArea[tile.Y][tile.X] = true
+		}
+		for y := 0; y < 128; y++ {
+			for x := 0; x < 128; x++ {
+				if !inArea[y][x] {
+					continue
+				}
+				runEnd := x
+				for runEnd < 127 && inArea[y][runEnd+1] {
+					runEnd++
+				}
+				entity.AddBrush(quakemap.BasicCuboid(
+					float64(x)*gridSizeX, float64(y)*-gridSizeY, floorDepth,
+					float64(runEnd+1)*gridSizeX, float64(y+1)*-gridSizeY, floorDepth+gridSizeZ,
+					"trigger", scale, false,
+				))
+				x = runEnd
+			}
+		}
+	}
+}
+
+// Adds info_intermission entities above the views picked by
+// IntermissionViews, pitched down toward the middle of the view
+func AddIntermissionCameras(rtlmap *RTLMapData, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+	var gridSizeZ float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+
+	cameraZ := floorDepth + float64(rtlmap.FloorHeight())*gridSizeZ - (gridSizeZ / 2)
+	for _, view := range rtlmap.IntermissionViews() {
+		log.Printf("Adding intermission camera at (%d,%d) facing %s", view.X, view.Y, view.Direction.Name())
+		pitch := math.Atan2(cameraZ-(floorDepth+gridSizeZ/2), float64(view.Distance)*gridSizeX/2) * 180.0 / math.Pi
+		entity := qm.SpawnEntity("info_intermission", 0)
+		entity.OriginX = (float64(view.X) + 0.5) * gridSizeX
+		entity.OriginY = (float64(view.Y) + 0.5) * -gridSizeY
+		entity.OriginZ = cameraZ
+		entity.AdditionalKeys["mangle"] = fmt.Sprintf("%.0f %d 0", pitch, int(view.Direction)*45)
+	}
+}
+
+func AddEnemies(rtlmap *RTLMapData, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
 	var gridSizeX float64 = 64.0 * scale
 	var gridSizeY float64 = 64.0 * scale
 	var gridSizeZ float64 = 64.0 * scale
@@ -1277,11 +1635,15 @@ func AddEnemies(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.Quake
 			actor := rtlmap.ActorGrid[y][x]
 			enemy := actor.Enemy
 			if enemy != nil {
-				entityName := enemy.ConversionInfo.EntityName(&actor, dusk)
-				if entityName == "" {
+				choice := enemy.ConversionInfo.EntityChoice(&actor, target)
+				if choice == nil {
 					continue
 				}
+				entityName := choice.Name
 				entity := qm.SpawnEntity(entityName, 0)
+				for k, v := range choice.Keys {
+					entity.AdditionalKeys[k] = v
+				}
 				AddDefaultEntityKeys(entity, &actor)
 				entity.OriginX = (float64(x) + 0.5) * gridSizeX
 				entity.OriginY = (float64(y) + 0.5) * -gridSizeY
@@ -1301,9 +1663,27 @@ func AddEnemies(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.Quake
 				}
 				entity.AdditionalKeys["angle"] = fmt.Sprintf("%.02f", angle)
 
-				switch enemy.Difficulty {
-				case DifficultyEasy:
-					entity.SpawnFlags = quakemap.SPAWNFLAG_NotOnHard
+				if enemy.ConversionInfo.Boss {
+					entity.AdditionalKeys["target"] = BossDeathTargetName
+					// id1 monsters set their health in their spawn
+					// functions and ignore the key, it only takes
+					// effect in mods and source ports reading it
+					if baseHealth, ok := MonsterBaseHealth[entityName]; ok && rtlmap.BossHealthScale > 0 {
+						entity.AdditionalKeys["health"] = fmt.Sprintf("%.0f", float64(baseHealth)*rtlmap.BossHealthScale)
+					}
+				}
+
+				if trigger, touchplateX, touchplateY := rtlmap.ActorTrigger(&rtlmap.ActorGrid[y][x], TRIGGER_EnemyActivate); trigger != nil {
+					// using a monster makes it go after the activator
+					enemyName := fmt.Sprintf("enemy_%d_%d", x, y)
+					entity.AdditionalKeys["targetname"] = enemyName
+					SpawnTriggerRelay(qm, entity.OriginX, entity.OriginY, floorDepth+gridSizeZ,
+						touchplateX, touchplateY, enemyName, trigger.Delay)
+				}
+
+				entity.SpawnFlags |= enemy.Difficulty.SpawnFlags(target)
+				if enemy.Ambush {
+					entity.SpawnFlags |= target.AmbushSpawnFlag()
 				}
 
 				// TODO: Z axis placement needs to be cleaned up. Lots
@@ -1326,12 +1706,185 @@ func AddEnemies(rtlmap *RTLMapData, scale float64, dusk bool, qm *quakemap.Quake
 				default:
 					entity.OriginZ = floorDepth + (gridSizeZ / 2.0)
 				}
+
+				// bosses stand their ground, their target is the
+				// death event opening the exit rather than a path
+				if enemy.Patrol && !enemy.ConversionInfo.Boss {
+					AddPatrolPath(rtlmap, &rtlmap.ActorGrid[y][x], entity, scale, qm)
+				}
+			}
+		}
+	}
+}
+
+// Adds a loop of path_corner entities following the enemy's patrol
+// route and points the monster at the first one
+func AddPatrolPath(rtlmap *RTLMapData, actor *ActorInfo, entity *quakemap.Entity, scale float64, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+
+	startNode, nodeCount := rtlmap.DeterminePatrolPath(actor, actor.Enemy.Direction)
+	if startNode == nil {
+		return
+	}
+	log.Printf("Enemy at (%d,%d) patrols %d corners", actor.X, actor.Y, nodeCount)
+
+	nodeToTargetNames := make(map[*PathNode]string)
+	var pathCorners []*quakemap.Entity
+	var pathNodes []*PathNode
+	for currentNode, i := startNode, 0; i < nodeCount; currentNode, i = currentNode.Next, i+1 {
+		targetName := fmt.Sprintf("patrol_%d_%d_%d", actor.X, actor.Y, i)
+		nodeToTargetNames[currentNode] = targetName
+		pathCorner := qm.SpawnEntity("path_corner", 0)
+		pathCorner.OriginX = (float64(currentNode.X) + 0.5) * gridSizeX
+		pathCorner.OriginY = (float64(currentNode.Y) + 0.5) * -gridSizeY
+		pathCorner.OriginZ = entity.OriginZ
+		pathCorner.AdditionalKeys["targetname"] = targetName
+		pathCorners = append(pathCorners, pathCorner)
+		pathNodes = append(pathNodes, currentNode)
+	}
+	for i, pathCorner := range pathCorners {
+		pathCorner.AdditionalKeys["target"] = nodeToTargetNames[pathNodes[i].Next]
+	}
+	entity.AdditionalKeys["target"] = nodeToTargetNames[startNode]
+}
+
+// Ends the level once every boss has been killed. Boss levels without
+// an exit of their own get one next to the first boss: a pillar with
+// the exit sign rises out of the floor and walking up to it teleports
+// the player to a sealed room under the map holding the exit. The
+// teleporter is what keeps the exit closed until then, id1 teleporters
+// with a targetname only work once fired.
+func AddBossExit(rtlmap *RTLMapData, scale float64, target TargetProfile, qm *quakemap.QuakeMap) {
+	var gridSizeX float64 = 64.0 * scale
+	var gridSizeY float64 = 64.0 * scale
+	var gridSizeZ float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+
+	bossCount := rtlmap.BossCount()
+	if bossCount == 0 {
+		return
+	}
+
+	hasExit := false
+	for _, point := range rtlmap.ExitPoints {
+		if !point.Secret {
+			hasExit = true
+		}
+	}
+
+	exitName := "boss_exit"
+	counterEntity := qm.SpawnEntity("trigger_counter", 0)
+	counterEntity.OriginX = (float64(rtlmap.SpawnX) + 0.5) * gridSizeX
+	counterEntity.OriginY = (float64(rtlmap.SpawnY) + 0.5) * -gridSizeY
+	counterEntity.OriginZ = floorDepth + gridSizeZ
+	counterEntity.SpawnFlags = 1 // no "n more to go" messages
+	counterEntity.AdditionalKeys["targetname"] = BossDeathTargetName
+	counterEntity.AdditionalKeys["count"] = fmt.Sprintf("%d", bossCount)
+	counterEntity.AdditionalKeys["message"] = "The way out is open!"
+	if hasExit {
+		return
+	}
+
+	exitX, exitY, ok := rtlmap.FloorNearBoss()
+	if !ok {
+		log.Printf("Boss level has no room for an exit next to the boss")
+		return
+	}
+	counterEntity.AdditionalKeys["target"] = exitName
+
+	destination := rtlmap.ExitDestination(ExitPoint{DestMap: rtlmap.MapNumber() + 1})
+	log.Printf("Boss level, killing %d boss(es) opens the exit at (%d,%d) leading to %s",
+		bossCount, exitX, exitY, destination)
+
+	// exit room, a tile in size with walls a tile thick
+	roomX1, roomY1, roomZ1 := 0.0, 0.0, -3*gridSizeZ
+	roomX2, roomY2, roomZ2 := gridSizeX, -gridSizeY, -2*gridSizeZ
+	wallTexture := rtlmap.FloorTexture()
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomX1-gridSizeX, roomY1+gridSizeY, roomZ1-gridSizeZ,
+		roomX2+gridSizeX, roomY2-gridSizeY, roomZ1, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomX1-gridSizeX, roomY1+gridSizeY, roomZ2,
+		roomX2+gridSizeX, roomY2-gridSizeY, roomZ2+gridSizeZ, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomX1-gridSizeX, roomY1+gridSizeY, roomZ1,
+		roomX1, roomY2-gridSizeY, roomZ2, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomX2, roomY1+gridSizeY, roomZ1,
+		roomX2+gridSizeX, roomY2-gridSizeY, roomZ2, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomX1, roomY1+gridSizeY, roomZ1,
+		roomX2, roomY1, roomZ2, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomX1, roomY2, roomZ1,
+		roomX2, roomY2-gridSizeY, roomZ2, wallTexture, scale, false))
+
+	destEntity := qm.SpawnEntity("info_teleport_destination", 0)
+	destEntity.OriginX = (roomX1 + roomX2) / 2
+	destEntity.OriginY = (roomY1 + roomY2) / 2
+	destEntity.OriginZ = roomZ1 + gridSizeZ/2.0
+	destEntity.AdditionalKeys["targetname"] = exitName + "_dest"
+
+	changeLevelEntity := qm.SpawnEntity("trigger_changelevel", 0)
+	changeLevelEntity.AdditionalKeys["map"] = destination
+	changeLevelEntity.AddBrush(quakemap.BasicCuboid(roomX1, roomY1, roomZ1, roomX2, roomY2, roomZ2, "trigger", scale, false))
+
+	// exit sign pillar in the middle of the tile, hidden in the floor
+	// until the last boss dies
+	x1 := float64(exitX) * gridSizeX
+	y1 := float64(exitY) * -gridSizeY
+	x2 := float64(exitX+1) * gridSizeX
+	y2 := float64(exitY+1) * -gridSizeY
+	margin := gridSizeX / 4.0
+	pillarEntity := qm.SpawnEntity("func_door", 0)
+	pillarEntity.AddBrush(quakemap.BasicCuboid(x1+margin, y1-margin, floorDepth-gridSizeZ,
+		x2-margin, y2+margin, floorDepth, exitLumps[0], scale, false))
+	pillarEntity.AdditionalKeys["targetname"] = exitName
+	pillarEntity.AdditionalKeys["angle"] = "-1"
+	pillarEntity.AdditionalKeys["lip"] = "0"
+	pillarEntity.AdditionalKeys["wait"] = "-1"
+	pillarEntity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", MovingObjectBaseSpeed*scale)
+
+	// player only
+	teleportEntity := qm.SpawnEntity("trigger_teleport", 1)
+	teleportEntity.AdditionalKeys["targetname"] = exitName
+	teleportEntity.AdditionalKeys["target"] = exitName + "_dest"
+	teleportEntity.AddBrush(quakemap.BasicCuboid(x1, y1, floorDepth,
+		x2, y2, floorDepth+gridSizeZ,
+		"trigger", scale, false))
+}
+
+// entity name prefixes of the pickups counted by ReportSkillSpawns
+var pickupEntityPrefixes = []string{"weapon_", "item_", "ammo_", "key_", "pickup_"}
+
+// logs how many monsters and pickups spawn on each skill level, path
+// corners, triggers and other map logic aren't counted
+func ReportSkillSpawns(qm *quakemap.QuakeMap) {
+	skills := []struct {
+		name        string
+		excludeFlag int
+	}{
+		{"easy", quakemap.SPAWNFLAG_NotOnEasy},
+		{"normal", quakemap.SPAWNFLAG_NotOnNormal},
+		{"hard", quakemap.SPAWNFLAG_NotOnHard},
+	}
+	for _, skill := range skills {
+		monsters, pickups := 0, 0
+		for _, entity := range qm.Entities {
+			if entity.SpawnFlags&skill.excludeFlag != 0 {
+				continue
+			}
+			if strings.HasPrefix(entity.ClassName, "monster_") {
+				monsters++
+				continue
+			}
+			for _, prefix := range pickupEntityPrefixes {
+				if strings.HasPrefix(entity.ClassName, prefix) {
+					pickups++
+					break
+				}
 			}
 		}
+		log.Printf("Skill %s spawns %d monsters and %d pickups", skill.name, monsters, pickups)
 	}
 }
 
-func ConvertRTLMapToQuakeMapFile(rtlmap *RTLMapData, textureWad string, scale float64, dusk bool, additionalWads []string, fgdFile string) *quakemap.QuakeMap {
+func ConvertRTLMapToQuakeMapFile(rtlmap *RTLMapData, textureWad string, scale float64, target TargetProfile, additionalWads []string, fgdFile string) *quakemap.QuakeMap {
 
 	// worldspawn:
 	// 1. build 128x128 floor
@@ -1358,6 +1911,7 @@ func ConvertRTLMapToQuakeMapFile(rtlmap *RTLMapData, textureWad string, scale fl
 
 	qm := quakemap.NewQuakeMap(playerStartX, playerStartY, floorDepth+32)
 	qm.InfoPlayerStart.Angle = playerAngle
+	qm.WorldSpawn.AdditionalKeys["message"] = rtlmap.MapName()
 	additionalWads = append(additionalWads, textureWad)
 	qm.Wads = additionalWads
 	if fgdFile != "" {
@@ -1391,34 +1945,43 @@ func ConvertRTLMapToQuakeMapFile(rtlmap *RTLMapData, textureWad string, scale fl
 			itemInfo := rtlmap.ActorGrid[y][x].Item
 
 			switch wallInfo.Type {
-			case WALL_Regular, WALL_Elevator:
+			case WALL_Regular, WALL_Elevator, WALL_Switch:
 				CreateRegularWall(rtlmap, x, y, scale, qm)
 			case WALL_ThinWall:
-				CreateThinWall(rtlmap, x, y, scale, dusk, qm)
+				CreateThinWall(rtlmap, x, y, scale, target, qm)
 			case WALL_AnimatedWall:
 				CreateRegularWall(rtlmap, x, y, scale, qm)
 			case WALL_Platform:
 				CreatePlatform(rtlmap, x, y, scale, qm)
+			case WALL_Window:
+				CreateWindow(rtlmap, x, y, scale, target, qm)
 			case WALL_MaskedWall:
-				CreateMaskedWall(rtlmap, x, y, scale, dusk, qm)
+				CreateMaskedWall(rtlmap, x, y, scale, target, qm)
 			case SPR_GAD:
-				CreateGAD(rtlmap, &wallInfo, scale, dusk, qm)
+				CreateGAD(rtlmap, &wallInfo, scale, target, qm)
 			}
 
 			if itemInfo != nil {
-				if itemInfo.AddCallback != nil {
-					itemInfo.AddCallback(x, y, gridSizeX, gridSizeY, gridSizeZ, itemInfo, rtlmap, qm, dusk)
-				} else {
-					entityName := itemInfo.QuakeEntityName
-					if dusk {
-						entityName = itemInfo.DuskEntityName
+				if itemInfo.UsesCallback(target) {
+					entity := itemInfo.AddCallback(x, y, gridSizeX, gridSizeY, gridSizeZ, itemInfo, rtlmap, qm, target)
+					if entity != nil {
+						for k, v := range itemInfo.EntityKeys(target) {
+							entity.AdditionalKeys[k] = v
+						}
 					}
+				} else {
+					entityName := itemInfo.EntityName(target)
 
 					if entityName == "" {
 						continue
 					}
 
+					// ROTT has no skill variants of items, they
+					// spawn on every skill level
 					entity := qm.SpawnEntity(entityName, 0)
+					for k, v := range itemInfo.EntityKeys(target) {
+						entity.AdditionalKeys[k] = v
+					}
 					entity.OriginX = float64(x)*gridSizeX + (gridSizeX / 2.0)
 					entity.OriginY = float64(y)*-gridSizeY - (gridSizeY / 2.0)
 					switch {
@@ -1443,11 +2006,8 @@ func ConvertRTLMapToQuakeMapFile(rtlmap *RTLMapData, textureWad string, scale fl
 					case wallInfo.InfoValue == 11, wallInfo.InfoValue == 12:
 						entity.OriginZ = floorDepth - 65.0 - float64(wallInfo.InfoValue-11)
 					case itemInfo.PlaceOnFloor == true:
-						if dusk {
-							entity.OriginZ = floorDepth + (float64(itemInfo.DuskHeight) / 2.0) + itemInfo.DuskZOffset
-						} else {
-							entity.OriginZ = floorDepth + (float64(itemInfo.QuakeHeight) / 2.0) + itemInfo.QuakeZOffset
-						}
+						height, zOffset := itemInfo.Placement(target)
+						entity.OriginZ = floorDepth + (height / 2.0) + zOffset
 					default:
 						entity.OriginZ = floorDepth + (gridSizeZ / 2.0)
 					}
@@ -1468,10 +2028,15 @@ func ConvertRTLMapToQuakeMapFile(rtlmap *RTLMapData, textureWad string, scale fl
 		}
 	}
 
-	CreateDoorEntities(rtlmap, scale, dusk, qm)
-	LinkElevators(rtlmap, textureWad, floorDepth, gridSizeX, gridSizeY, gridSizeZ, scale, dusk, qm)
-	AddExitPoints(rtlmap, scale, dusk, qm)
-	AddEnemies(rtlmap, scale, dusk, qm)
+	CreateDoorEntities(rtlmap, scale, target, qm)
+	LinkElevators(rtlmap, textureWad, floorDepth, gridSizeX, gridSizeY, gridSizeZ, scale, target, qm)
+	AddExitPoints(rtlmap, scale, target, qm)
+	AddSecretAreas(rtlmap, scale, target, qm)
+	AddIntermissionCameras(rtlmap, scale, target, qm)
+	AddEnemies(rtlmap, scale, target, qm)
+	AddBossExit(rtlmap, scale, target, qm)
+	target.FinishMap(qm)
+	ReportSkillSpawns(qm)
 
 	// 2. TODO: clip brushes around floor extending height
 	return qm
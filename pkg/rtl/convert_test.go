@@ -0,0 +1,185 @@
+package rtl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden .map files in testdata/")
+
+// writes the plane with RLEW compression, runs of 4 or more
+// identical values are stored as tag, count and value
+func writeTestPlane(buf *bytes.Buffer, plane *[128][128]uint16, rlewTag uint16) {
+	values := make([]uint16, 0, 128*128)
+	for y := 0; y < 128; y++ {
+		values = append(values, plane[y][:]...)
+	}
+	for i := 0; i < len(values); {
+		run := 1
+		for i+run < len(values) && values[i+run] == values[i] {
+			run++
+		}
+		if run >= 4 || values[i] == rlewTag {
+			binary.Write(buf, binary.LittleEndian, []uint16{rlewTag, uint16(run), values[i]})
+		} else {
+			for j := 0; j < run; j++ {
+				binary.Write(buf, binary.LittleEndian, values[i])
+			}
+		}
+		i += run
+	}
+}
+
+// returns an RTL file holding a single map: two rooms joined by a
+// door, with the player start, a weapon, a guard and an exit
+func testRTLFile() []byte {
+	return testRTLFileWith(nil)
+}
+
+// returns the test map of testRTLFile, changed by edit before writing it
+func testRTLFileWith(edit func(wallPlane, spritePlane, infoPlane *[128][128]uint16)) []byte {
+	const rlewTag = 0xabcd
+	var wallPlane, spritePlane, infoPlane [128][128]uint16
+
+	// metadata: floor, ceiling, brightness, light fade rate and height
+	wallPlane[0][0] = 180
+	wallPlane[0][1] = 198
+	wallPlane[0][2] = 5
+	wallPlane[0][3] = 5
+	spritePlane[0][0] = 90
+
+	for y := 1; y <= 12; y++ {
+		for x := 1; x <= 10; x++ {
+			if x == 1 || x == 10 || y == 1 || y == 12 || y == 6 {
+				wallPlane[y][x] = 1
+			} else {
+				wallPlane[y][x] = AreaTileMin + 1
+			}
+		}
+	}
+	wallPlane[6][5] = 33     // door
+	spritePlane[3][3] = 19   // player start, facing north
+	spritePlane[4][6] = 0x2e // bat
+	spritePlane[9][7] = 108  // low guard
+	infoPlane[10][8] = 0xe201
+	if edit != nil {
+		edit(&wallPlane, &spritePlane, &infoPlane)
+	}
+
+	var planes bytes.Buffer
+	var offsets [3]uint32
+	var lengths [3]uint32
+	for i, plane := range []*[128][128]uint16{&wallPlane, &spritePlane, &infoPlane} {
+		offsets[i] = uint32(planes.Len())
+		writeTestPlane(&planes, plane, rlewTag)
+		lengths[i] = uint32(planes.Len()) - offsets[i]
+	}
+	// unused maps share an empty plane
+	emptyOffset := uint32(planes.Len())
+	binary.Write(&planes, binary.LittleEndian, []uint16{rlewTag, 128 * 128, 0})
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, RTLHeader{Signature: rtlMagic, Version: 0x101})
+	dataOffset := uint32(binary.Size(RTLHeader{}) + 100*binary.Size(RTLMapHeader{}))
+	for i := 0; i < 100; i++ {
+		header := RTLMapHeader{
+			RLEWTag:           rlewTag,
+			WallPlaneOffset:   dataOffset + emptyOffset,
+			SpritePlaneOffset: dataOffset + emptyOffset,
+			InfoPlaneOffset:   dataOffset + emptyOffset,
+		}
+		if i == 0 {
+			header.Used = 1
+			header.WallPlaneOffset = dataOffset + offsets[0]
+			header.SpritePlaneOffset = dataOffset + offsets[1]
+			header.InfoPlaneOffset = dataOffset + offsets[2]
+			header.WallPlaneLength = lengths[0]
+			header.SpritePlaneLength = lengths[1]
+			header.InfoPlaneLength = lengths[2]
+			copy(header.Name[:], "TEST MAP")
+		}
+		binary.Write(&buf, binary.LittleEndian, header)
+	}
+	buf.Write(planes.Bytes())
+	return buf.Bytes()
+}
+
+// converts the test map for every target and compares the result with
+// testdata/<target>.map, run with -update after intended changes
+func TestConvertRTLMapGolden(t *testing.T) {
+	for _, name := range TargetProfileNames() {
+		t.Run(name, func(t *testing.T) {
+			target, err := GetTargetProfile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rtl, err := NewRTL(bytes.NewReader(testRTLFile()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered := ConvertRTLMapToQuakeMapFile(&rtl.MapData[0], "rott.wad", 1.0, target, nil, "").Render()
+
+			goldenFile := filepath.Join("testdata", name+".map")
+			if *updateGolden {
+				if err := ioutil.WriteFile(goldenFile, []byte(rendered), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := ioutil.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rendered != string(golden) {
+				t.Errorf("%s differs from the converted map, rerun with -update if the change is intended", goldenFile)
+			}
+
+			// converting again gives the same map
+			rtl, _ = NewRTL(bytes.NewReader(testRTLFile()))
+			if again := ConvertRTLMapToQuakeMapFile(&rtl.MapData[0], "rott.wad", 1.0, target, nil, "").Render(); again != rendered {
+				t.Errorf("converting the map twice gave different results")
+			}
+		})
+	}
+}
+
+// returns the entities of the class
+func entitiesOfClass(qm *quakemap.QuakeMap, className string) []*quakemap.Entity {
+	var entities []*quakemap.Entity
+	for _, entity := range qm.Entities {
+		if entity.ClassName == className {
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+// Quake 2's trigger_key only fires once, locked doors have to stay open
+func TestConvertQuake2LockedDoorStaysOpen(t *testing.T) {
+	rtl, err := NewRTL(bytes.NewReader(testRTLFileWith(func(wallPlane, spritePlane, infoPlane *[128][128]uint16) {
+		wallPlane[6][5] = 94     // gold key door
+		spritePlane[4][8] = 0x1d // gold key
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := GetTargetProfile("quake2")
+	qm := ConvertRTLMapToQuakeMapFile(&rtl.MapData[0], "rott.wad", 1.0, target, nil, "")
+
+	doors := entitiesOfClass(qm, "func_door")
+	if len(doors) != 1 {
+		t.Fatalf("expected 1 door, got %d", len(doors))
+	}
+	if wait := doors[0].AdditionalKeys["wait"]; wait != "-1" {
+		t.Errorf("expected the locked door to wait -1, got %q", wait)
+	}
+	keys := entitiesOfClass(qm, "trigger_key")
+	if len(keys) != 1 || keys[0].AdditionalKeys["target"] != doors[0].AdditionalKeys["targetname"] {
+		t.Errorf("expected a trigger_key opening the door, got %d", len(keys))
+	}
+}
@@ -5,9 +5,9 @@ import (
 )
 
 type DoorTexInfo struct {
-	BaseTexture string
-	SideTexture string
-	AltTexture  string
+	BaseTexture string `json:"base_texture"`
+	SideTexture string `json:"side_texture"`
+	AltTexture  string `json:"alt_texture"`
 }
 
 type DoorLock int
@@ -36,10 +36,88 @@ func (d DoorLock) KeyName() string {
 	}
 }
 
+// how a door moves once opened
+type DoorBehavior struct {
+	Speed  float64 // units per second at 1x scale
+	Wait   float64 // seconds before closing again, -1 stays open
+	Sounds int     // func_door "sounds" (1: stone, 2: machine, 4: screechy metal)
+}
+
+const (
+	// ROTT's door code descends from Wolfenstein 3D's (WL_ACT1.C): a
+	// door slides its 64 units open over 64 tics and waits OPENTICS
+	// (300 tics) before closing, at 70 tics per second
+	doorTicsPerSecond = 70.0
+	doorOpenTics      = 64.0
+	doorWaitTics      = 300.0
+	doorTravel        = 64.0
+)
+
+var (
+	// all doors share ROTT's timing and only differ in how they sound
+	RegularDoorBehavior  = DoorBehavior{doorTravel * doorTicsPerSecond / doorOpenTics, doorWaitTics / doorTicsPerSecond, 2}
+	ElevatorDoorBehavior = DoorBehavior{doorTravel * doorTicsPerSecond / doorOpenTics, doorWaitTics / doorTicsPerSecond, 4}
+	// snake/TNT doors are the heavy stone kind
+	HeavyDoorBehavior = DoorBehavior{doorTravel * doorTicsPerSecond / doorOpenTics, doorWaitTics / doorTicsPerSecond, 1}
+)
+
+func (d DoorLock) Message() string {
+	switch d {
+	case LOCK_GoldKey, LOCK_SilverKey, LOCK_IronKey, LOCK_OscuroKey:
+		return fmt.Sprintf("You need the %s key.", d.KeyName())
+	default:
+		return ""
+	}
+}
+
+// how locked doors and their keys are converted
+type KeyStrategy int
+
+const (
+	// item keys, switching to triggered keys when the map uses more
+	// locks than the target has keys
+	KEYS_Auto KeyStrategy = iota
+	// target key items, locks share keys once they run out
+	KEYS_Items
+	// key pickups fire triggers that unlock their doors, allows for
+	// all four ROTT keys in Quake's two
+	KEYS_Triggered
+)
+
+func ParseKeyStrategy(name string) (KeyStrategy, error) {
+	switch name {
+	case "auto":
+		return KEYS_Auto, nil
+	case "items":
+		return KEYS_Items, nil
+	case "triggered":
+		return KEYS_Triggered, nil
+	default:
+		return KEYS_Auto, fmt.Errorf("unknown key strategy %q (auto, items, triggered)", name)
+	}
+}
+
+// name fired by the pickups of a triggered key
+func (d DoorLock) KeyTargetName() string {
+	return fmt.Sprintf("key_%d", int(d))
+}
+
+// returns the number of distinct keyed locks used on the map
+func (r *RTLMapData) KeyedLockCount() int {
+	locks := make(map[DoorLock]bool)
+	for _, door := range r.Doors {
+		if door.Lock != LOCK_Unlocked && door.Lock != LOCK_Trigger {
+			locks[door.Lock] = true
+		}
+	}
+	return len(locks)
+}
+
 type Door struct {
 	Lock      DoorLock
 	TriggerX  int
 	TriggerY  int
+	OpenDelay int // seconds before a timed door opens, 0 if it isn't one
 	Direction WallDirection
 	Tiles     []ActorInfo
 }
@@ -85,6 +163,101 @@ func GetDoorTextures(tileID uint16) *DoorTexInfo {
 	}
 }
 
+// door tiles with an info value pointing at a touchplate are opened by
+// it, otherwise the value is the delay of a timed door
+func (r *RTLMapData) DoorTouchplate(actor *ActorInfo) (int, int, bool) {
+	return r.TouchplateLocation(actor.InfoValue)
+}
+
+// returns how the door moves, touchplate triggered and timed doors
+// stay open once opened
+func (d *Door) Behavior() DoorBehavior {
+	behavior := RegularDoorBehavior
+	if texInfo := GetDoorTextures(d.Tiles[0].Tile); texInfo != nil {
+		switch texInfo.BaseTexture {
+		case "EDOOR":
+			behavior = ElevatorDoorBehavior
+		case "SNDOOR", "SNADOOR", "SNKDOOR", "TNDOOR", "TNADOOR", "TNKDOOR":
+			behavior = HeavyDoorBehavior
+		}
+	}
+	if d.Lock == LOCK_Trigger || d.OpenDelay > 0 {
+		behavior.Wait = -1
+	}
+	return behavior
+}
+
+// returns the door occupying the tile (if any)
+func (r *RTLMapData) DoorAt(x, y int) *Door {
+	for i, door := range r.Doors {
+		for _, doorTile := range door.Tiles {
+			if doorTile.X == x && doorTile.Y == y {
+				return &r.Doors[i]
+			}
+		}
+	}
+	return nil
+}
+
+// returns the texture for the side of the door frame, locked doors
+// have their lock drawn on the frame
+func (d *Door) SideTexture() string {
+	switch d.Lock {
+	case LOCK_GoldKey, LOCK_SilverKey, LOCK_IronKey, LOCK_OscuroKey:
+		return fmt.Sprintf("LOCK%d", int(d.Lock))
+	}
+	texInfo := GetDoorTextures(d.Tiles[0].Tile)
+	if texInfo == nil {
+		return ""
+	}
+	return texInfo.SideTexture
+}
+
+// returns the side texture to use on the face of the wall at (x, y)
+// facing the direction if it frames a door or masked wall, otherwise
+// an empty string
+func (r *RTLMapData) JambTexture(x, y int, direction WallDirection) string {
+	deltaX, deltaY := direction.Delta()
+	neighborX, neighborY := x+deltaX, y+deltaY
+	if neighborX < 0 || neighborX > 127 || neighborY < 0 || neighborY > 127 {
+		return ""
+	}
+
+	// only walls in line with the door/masked wall frame it
+	var frameDirection WallDirection
+	switch direction {
+	case DIR_North, DIR_South:
+		frameDirection = WALLDIR_NorthSouth
+	case DIR_East, DIR_West:
+		frameDirection = WALLDIR_EastWest
+	default:
+		return ""
+	}
+
+	neighbor := &r.ActorGrid[neighborY][neighborX]
+	switch neighbor.Type {
+	case WALL_Door:
+		if door := r.DoorAt(neighborX, neighborY); door != nil && door.Direction == frameDirection {
+			return door.SideTexture()
+		}
+	case WALL_MaskedWall:
+		if thinWallDirection, _, _ := r.ThinWallDirection(neighborX, neighborY); thinWallDirection == frameDirection {
+			return MaskedWalls[neighbor.Tile].Side
+		}
+	}
+	return ""
+}
+
+func (r *RTLMapData) determineDoors() {
+	r.Doors = r.GetDoors()
+	for _, door := range r.Doors {
+		if door.Lock == LOCK_Trigger {
+			doorTile := door.Tiles[0]
+			r.AddTrigger(&r.ActorGrid[doorTile.Y][doorTile.X], door.TriggerX, door.TriggerY, TRIGGER_DoorOpen)
+		}
+	}
+}
+
 func (r *RTLMapData) GetDoors() []Door {
 	mapTileToDoor := make(map[string]*Door)
 	var doors []Door
@@ -111,7 +284,13 @@ func (r *RTLMapData) GetDoors() []Door {
 					newDoor.Lock = DoorLock(int(r.SpritePlane[y][x] - 0x1c))
 				}
 
-				// TODO: touchplate trigger locations?
+				// touchplate triggered doors can only be opened
+				// by their touchplate, keyed doors keep their lock
+				if triggerX, triggerY, ok := r.DoorTouchplate(&r.ActorGrid[y][x]); ok && newDoor.Lock == LOCK_Unlocked {
+					newDoor.Lock = LOCK_Trigger
+					newDoor.TriggerX = triggerX
+					newDoor.TriggerY = triggerY
+				}
 
 				// find adjacent door tiles north of it
 				if y > 0 && r.ActorGrid[y-1][x].Type == WALL_Door {
@@ -218,6 +397,16 @@ func (r *RTLMapData) GetDoors() []Door {
 					newDoor.Direction, _, _ = r.ThinWallDirection(x, y)
 				}
 
+				// any other info value is the minutes before a timed
+				// door opens
+				if newDoor.Lock != LOCK_Trigger {
+					for _, doorTile := range newDoor.Tiles {
+						if _, _, isTriggered := r.DoorTouchplate(&doorTile); doorTile.InfoValue > 0 && !isTriggered {
+							newDoor.OpenDelay = int(doorTile.InfoValue>>8) * 60
+						}
+					}
+				}
+
 				doors = append(doors, newDoor)
 			}
 		}
@@ -1,38 +1,117 @@
 package rtl
 
+import (
+	"math/rand"
+)
+
 type Difficulty int
 
+// ROTT's skill levels map onto Quake's as baby and easy to easy,
+// medium to normal, and hard ("Crezzy Man") to hard
 var (
-	DifficultyEasy Difficulty = 1 // basically represents difficulties below "Crezzy Man"
-	DifficultyHard Difficulty = 2
+	DifficultyAll  Difficulty = 0 // spawns on every skill level
+	DifficultyEasy Difficulty = 1 // base tier of enemies, spawns on every skill level
+	DifficultyHard Difficulty = 2 // only spawns on hard (rt_ted.c, gd_hard)
 )
 
+// returns the target's spawnflags keeping an entity out of the skill
+// levels it should not appear on
+func (d Difficulty) SpawnFlags(target TargetProfile) int {
+	notOnEasy, notOnNormal, _ := target.NotOnSkillFlags()
+	switch d {
+	case DifficultyHard:
+		return notOnEasy | notOnNormal
+	default:
+		return 0
+	}
+}
+
 type EnemyConversionInfo struct {
 	QuakeEnemyNames []string
 	DuskEnemyNames  []string
+	Boss            bool // killing it ends the level
+	// weighted entities keyed by target name, takes precedence over
+	// the target's default names
+	Choices map[string][]EntityChoice
+}
+
+// a replacement entity and how likely it is to be picked
+type EntityChoice struct {
+	Name   string            `json:"name"`
+	Weight float64           `json:"weight,omitempty"` // defaults to 1
+	Keys   map[string]string `json:"keys,omitempty"`
+}
+
+// seeds the choice between replacement entities, the same seed
+// always converts a map the same way. Without a seed or weights the
+// choice only depends on the enemy's position.
+var EntitySeed *int64
+
+const (
+	// fired by bosses when killed, see AddBossExit
+	BossDeathTargetName = "boss_killed"
+)
+
+// default health of boss-tier monsters, for scaling it up through the
+// "health" key (honored by most mods and source ports)
+var MonsterBaseHealth = map[string]int{
+	"monster_shambler":    600,
+	"monster_shalrath":    400,
+	"monster_hell_knight": 250,
 }
 
-func (e *EnemyConversionInfo) EntityName(actor *ActorInfo, dusk bool) string {
+func (e *EnemyConversionInfo) EntityChoice(actor *ActorInfo, target TargetProfile) *EntityChoice {
+	choices := e.Choices[target.Name()]
+	if len(choices) == 0 {
+		for _, name := range target.EnemyEntityNames(e) {
+			choices = append(choices, EntityChoice{Name: name})
+		}
+	}
+	if len(choices) == 0 {
+		return nil
+	}
+
 	// provide some variety in the enemies spawned but keep it
 	// deterministic
-	enemyPool := e.QuakeEnemyNames
-	if dusk {
-		enemyPool = e.DuskEnemyNames
+	totalWeight := 0.0
+	weighted := false
+	for _, choice := range choices {
+		totalWeight += choice.weight()
+		weighted = weighted || choice.Weight > 0
+	}
+	if EntitySeed == nil && !weighted {
+		return &choices[(actor.X+actor.Y)%len(choices)]
 	}
-	if len(enemyPool) == 0 {
-		return ""
+	var seed int64
+	if EntitySeed != nil {
+		seed = *EntitySeed
 	}
-	return enemyPool[(actor.X+actor.Y)%len(enemyPool)]
+	pick := rand.New(rand.NewSource(seed+int64(actor.Y*128+actor.X))).Float64() * totalWeight
+	for i := range choices {
+		pick -= choices[i].weight()
+		if pick < 0 {
+			return &choices[i]
+		}
+	}
+	return &choices[len(choices)-1]
+}
+
+func (c *EntityChoice) weight() float64 {
+	if c.Weight <= 0 {
+		return 1.0
+	}
+	return c.Weight
 }
 
 type EnemyInfo struct {
 	Direction      WallDirection
+	Patrol         bool // walks along the arrows on the map
+	Ambush         bool // lies in wait until the player comes along
 	Difficulty     Difficulty
 	ConversionInfo EnemyConversionInfo
 }
 
-// TODO: this needs to be configuration-driven
-
+// defaults, see LoadMappingConfig for overriding them
 var Enemies = map[string]EnemyConversionInfo{
 	"low_guard": EnemyConversionInfo{
 		QuakeEnemyNames: []string{"monster_army"},
@@ -50,6 +129,10 @@ var Enemies = map[string]EnemyConversionInfo{
 		QuakeEnemyNames: []string{"monster_wizard"},
 		DuskEnemyNames:  []string{"monster_mage_red"},
 	},
+	"strike_guard": EnemyConversionInfo{
+		QuakeEnemyNames: []string{"monster_enforcer"},
+		DuskEnemyNames:  []string{"monster_cowgirl"},
+	},
 	"triad_enforcer": EnemyConversionInfo{
 		QuakeEnemyNames: []string{"monster_shambler"},
 		DuskEnemyNames:  []string{"monster_hell_knight"},
@@ -82,7 +165,70 @@ var Enemies = map[string]EnemyConversionInfo{
 		QuakeEnemyNames: []string{"monster_dog"},
 		DuskEnemyNames:  []string{"monster_turret"},
 	},
-	// TODO: bosses
+	"general_darian": EnemyConversionInfo{
+		QuakeEnemyNames: []string{"monster_shambler"},
+		DuskEnemyNames:  []string{"monster_hell_knight"},
+		Boss:            true,
+	},
+	"sebastian_krist": EnemyConversionInfo{
+		QuakeEnemyNames: []string{"monster_shalrath"},
+		DuskEnemyNames:  []string{"monster_priestess"},
+		Boss:            true,
+	},
+	"nme": EnemyConversionInfo{
+		QuakeEnemyNames: []string{"monster_shambler"},
+		DuskEnemyNames:  []string{"monster_wendigo"},
+		Boss:            true,
+	},
+	"el_oscuro": EnemyConversionInfo{
+		QuakeEnemyNames: []string{"monster_shalrath"},
+		DuskEnemyNames:  []string{"monster_wendigo"},
+		Boss:            true,
+	},
+}
+
+// returns the number of bosses on the map
+func (r *RTLMapData) BossCount() int {
+	count := 0
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if r.ActorGrid[y][x].Enemy != nil && r.ActorGrid[y][x].Enemy.ConversionInfo.Boss {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// returns the tile of a free floor tile closest to the first boss on
+// the map, false if there is none
+func (r *RTLMapData) FloorNearBoss() (int, int, bool) {
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if r.ActorGrid[y][x].Enemy == nil || !r.ActorGrid[y][x].Enemy.ConversionInfo.Boss {
+				continue
+			}
+			// search rings of growing distance around the boss,
+			// skipping the boss' own tile
+			for distance := 1; distance < 128; distance++ {
+				for fy := y - distance; fy <= y+distance; fy++ {
+					for fx := x - distance; fx <= x+distance; fx++ {
+						if fx < 1 || fx > 126 || fy < 1 || fy > 126 ||
+							(fx != x-distance && fx != x+distance && fy != y-distance && fy != y+distance) {
+							continue
+						}
+						actor := &r.ActorGrid[fy][fx]
+						if r.IsFloor(fx, fy) && !actor.IsWall() && actor.Enemy == nil && actor.Item == nil &&
+							len(actor.MapTriggers) == 0 && (fx != r.SpawnX || fy != r.SpawnY) {
+							return fx, fy, true
+						}
+					}
+				}
+			}
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
 }
 
 func GetEnemyInfoFromSpriteValue(spriteValue uint16) *EnemyInfo {
@@ -90,6 +236,8 @@ func GetEnemyInfoFromSpriteValue(spriteValue uint16) *EnemyInfo {
 	var enemyInfo EnemyInfo
 	var difficulty Difficulty
 	var direction int
+	var patrol bool // the second set of four directions in a range
+	var ambush bool
 
 	// rt_ted.c:4592
 	switch s := spriteValue; {
@@ -97,73 +245,93 @@ func GetEnemyInfoFromSpriteValue(spriteValue uint16) *EnemyInfo {
 		enemyName = "low_guard"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-108) % 4
+		patrol = int(spriteValue-108)/4 == 1
 	case s >= 126 && s <= 137:
 		enemyName = "low_guard"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-126) % 4
+		patrol = int(spriteValue-126)/4 == 1
 	case s == 120:
+		// plays dead until the player walks by
 		enemyName = "sneaky_low_guard"
 		difficulty = DifficultyEasy
+		ambush = true
 	case s == 138:
 		enemyName = "sneaky_low_guard"
 		difficulty = DifficultyHard
+		ambush = true
 	case s >= 144 && s <= 155:
 		enemyName = "high_guard"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-144) % 4
+		patrol = int(spriteValue-144)/4 == 1
 	case s == 120:
 	case s >= 162 && s <= 173:
 		enemyName = "high_guard"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-162) % 4
+		patrol = int(spriteValue-162)/4 == 1
 	case s == 120:
 	case s >= 216 && s <= 227:
 		enemyName = "overpatrol_guard"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-216) % 4
+		patrol = int(spriteValue-216)/4 == 1
 	case s >= 234 && s <= 245:
 		enemyName = "overpatrol_guard"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-234) % 4
+		patrol = int(spriteValue-234)/4 == 1
 	case s >= 180 && s <= 191:
 		enemyName = "strike_guard"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-180) % 4
-	case s >= 198 && s <= 204:
+		patrol = int(spriteValue-180)/4 == 1
+	case s >= 198 && s <= 209:
 		enemyName = "strike_guard"
 		difficulty = DifficultyHard
+		direction = int(spriteValue-198) % 4
+		patrol = int(spriteValue-198)/4 == 1
 	case s >= 288 && s <= 299:
 		enemyName = "triad_enforcer"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-288) % 4
+		patrol = int(spriteValue-288)/4 == 1
 	case s >= 306 && s <= 317:
 		enemyName = "triad_enforcer"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-306) % 4
+		patrol = int(spriteValue-306)/4 == 1
 	case s >= 324 && s <= 335:
 		enemyName = "lightning_guard"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-324) % 4
+		patrol = int(spriteValue-324)/4 == 1
 	case s >= 342 && s <= 353:
 		enemyName = "lightning_guard"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-342) % 4
+		patrol = int(spriteValue-342)/4 == 1
 	case s >= 360 && s <= 371:
 		enemyName = "monk"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-360) % 4
+		patrol = int(spriteValue-360)/4 == 1
 	case s >= 378 && s <= 389:
 		enemyName = "monk"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-378) % 4
+		patrol = int(spriteValue-378)/4 == 1
 	case s >= 396 && s <= 407:
 		enemyName = "fire_monk"
 		difficulty = DifficultyEasy
 		direction = int(spriteValue-396) % 4
+		patrol = int(spriteValue-396)/4 == 1
 	case s >= 414 && s <= 425:
 		enemyName = "fire_monk"
 		difficulty = DifficultyHard
 		direction = int(spriteValue-414) % 4
+		patrol = int(spriteValue-414)/4 == 1
 	case s >= 158 && s <= 161:
 		enemyName = "robo_guard"
 		difficulty = DifficultyEasy
@@ -188,6 +356,21 @@ func GetEnemyInfoFromSpriteValue(spriteValue uint16) *EnemyInfo {
 	case s == 211:
 		enemyName = "4_way_gun"
 		difficulty = DifficultyHard
+	// bosses, rt_ted.c (SpawnInitialActors), have a single sprite
+	// and show up on every skill level
+	case s == 99:
+		enemyName = "general_darian"
+		difficulty = DifficultyAll
+	case s == 100:
+		enemyName = "sebastian_krist"
+		difficulty = DifficultyAll
+	case s == 101, s == 103:
+		// dark monk and snake forms
+		enemyName = "el_oscuro"
+		difficulty = DifficultyAll
+	case s == 102:
+		enemyName = "nme"
+		difficulty = DifficultyAll
 	default:
 		return nil
 	}
@@ -195,5 +378,7 @@ func GetEnemyInfoFromSpriteValue(spriteValue uint16) *EnemyInfo {
 	enemyInfo.Difficulty = difficulty
 	enemyInfo.ConversionInfo = Enemies[enemyName]
 	enemyInfo.Direction = WallDirection(direction * 2)
+	enemyInfo.Patrol = patrol
+	enemyInfo.Ambush = ambush
 	return &enemyInfo
 }
@@ -0,0 +1,58 @@
+package rtl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetEnemyInfoFromSpriteValue(t *testing.T) {
+	tests := []struct {
+		spriteValue uint16
+		enemyName   string
+		difficulty  Difficulty
+	}{
+		{108, "low_guard", DifficultyEasy},
+		{137, "low_guard", DifficultyHard},
+		{120, "sneaky_low_guard", DifficultyEasy},
+		{138, "sneaky_low_guard", DifficultyHard},
+		{144, "high_guard", DifficultyEasy},
+		{173, "high_guard", DifficultyHard},
+		{216, "overpatrol_guard", DifficultyEasy},
+		{245, "overpatrol_guard", DifficultyHard},
+		{191, "strike_guard", DifficultyEasy},
+		{209, "strike_guard", DifficultyHard},
+		{288, "triad_enforcer", DifficultyEasy},
+		{317, "triad_enforcer", DifficultyHard},
+		{324, "lightning_guard", DifficultyEasy},
+		{353, "lightning_guard", DifficultyHard},
+		{360, "monk", DifficultyEasy},
+		{389, "monk", DifficultyHard},
+		{396, "fire_monk", DifficultyEasy},
+		{425, "fire_monk", DifficultyHard},
+		{158, "robo_guard", DifficultyEasy},
+		{179, "robo_guard", DifficultyHard},
+		{408, "ballistikraft", DifficultyEasy},
+		{429, "ballistikraft", DifficultyHard},
+		{194, "gun_emplacement", DifficultyEasy},
+		{215, "gun_emplacement", DifficultyHard},
+		{89, "4_way_gun", DifficultyEasy},
+		{211, "4_way_gun", DifficultyHard},
+		{99, "general_darian", DifficultyAll},
+		{100, "sebastian_krist", DifficultyAll},
+		{101, "el_oscuro", DifficultyAll},
+		{102, "nme", DifficultyAll},
+	}
+	for _, test := range tests {
+		enemy := GetEnemyInfoFromSpriteValue(test.spriteValue)
+		if enemy == nil {
+			t.Errorf("sprite %d: expected %s, got no enemy", test.spriteValue, test.enemyName)
+			continue
+		}
+		if !reflect.DeepEqual(enemy.ConversionInfo, Enemies[test.enemyName]) {
+			t.Errorf("sprite %d: expected %s, got %v", test.spriteValue, test.enemyName, enemy.ConversionInfo)
+		}
+		if enemy.Difficulty != test.difficulty {
+			t.Errorf("sprite %d: expected difficulty %d, got %d", test.spriteValue, test.difficulty, enemy.Difficulty)
+		}
+	}
+}
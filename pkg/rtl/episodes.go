@@ -0,0 +1,175 @@
+package rtl
+
+// Episode structure of RTL files and how maps link together
+
+import (
+	"fmt"
+	"log"
+
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
+const (
+	// rt_game.c (GetEpisode), the fourth episode has the remaining maps
+	MapsPerEpisode = 8
+	NumEpisodes    = 4
+
+	// generated hub map leading to the first map of every episode
+	StartMapName = "start"
+)
+
+// how converted map files are named
+type MapNameScheme int
+
+const (
+	MAPNAMES_Numbered MapNameScheme = iota // map001, map002, ...
+	MAPNAMES_Episode                       // e1m1, e1m2, ...
+)
+
+func ParseMapNameScheme(name string) (MapNameScheme, error) {
+	switch name {
+	case "numbered":
+		return MAPNAMES_Numbered, nil
+	case "episode":
+		return MAPNAMES_Episode, nil
+	default:
+		return MAPNAMES_Numbered, fmt.Errorf("unknown map name scheme %q (numbered, episode)", name)
+	}
+}
+
+// returns the episode of the 1-based map number
+func MapEpisode(mapNumber int) int {
+	episode := (mapNumber-1)/MapsPerEpisode + 1
+	if episode > NumEpisodes {
+		return NumEpisodes
+	}
+	return episode
+}
+
+// returns the file name (without extension) of the 1-based map number
+func (s MapNameScheme) MapFileName(mapNumber int) string {
+	switch s {
+	case MAPNAMES_Episode:
+		episode := MapEpisode(mapNumber)
+		return fmt.Sprintf("e%dm%d", episode, mapNumber-(episode-1)*MapsPerEpisode)
+	default:
+		return fmt.Sprintf("map%03d", mapNumber)
+	}
+}
+
+// returns the 1-based number of the map within its RTL file
+func (r *RTLMapData) MapNumber() int {
+	for i := range r.rtl.MapData {
+		if &r.rtl.MapData[i] == r {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// returns true if the 1-based map number exists in the RTL file
+func (r *RTL) MapUsed(mapNumber int) bool {
+	return mapNumber >= 1 && mapNumber <= len(r.MapData) && r.MapData[mapNumber-1].Header.Used != 0
+}
+
+// returns the map an exit leads to, leaving the episode (or the RTL
+// file) returns the player to the start map
+func (r *RTLMapData) ExitDestination(point ExitPoint) string {
+	if !r.rtl.MapUsed(point.DestMap) {
+		log.Printf("Exit at (%d,%d) leads to missing map %d, ending episode", point.X, point.Y, point.DestMap)
+		return StartMapName
+	}
+	if !point.Secret && MapEpisode(point.DestMap) != MapEpisode(r.MapNumber()) {
+		// secret levels may be filed under another episode, but
+		// regular exits only lead on within the episode
+		return StartMapName
+	}
+	return r.MapNameScheme.MapFileName(point.DestMap)
+}
+
+// returns the first map of each episode in the RTL file, indexed by
+// episode number
+func (r *RTL) EpisodeStartMaps() map[int]int {
+	startMaps := make(map[int]int)
+	for i := range r.MapData {
+		mapNumber := i + 1
+		if !r.MapUsed(mapNumber) {
+			continue
+		}
+		if _, ok := startMaps[MapEpisode(mapNumber)]; !ok {
+			startMaps[MapEpisode(mapNumber)] = mapNumber
+		}
+	}
+	return startMaps
+}
+
+// Generates the start map: a corridor with an exit into the first map
+// of every episode, marked by the textures of that map.
+func CreateStartMap(r *RTL, textureWad string, scale float64, scheme MapNameScheme, target TargetProfile, additionalWads []string, fgdFile string) *quakemap.QuakeMap {
+	var gridSize float64 = 64.0 * scale
+	var floorDepth float64 = 64.0 * scale
+	var roomHeight float64 = 2 * gridSize
+
+	startMaps := r.EpisodeStartMaps()
+
+	// one 2x2 tile bay per episode, with a tile of wall in between
+	roomLength := float64(NumEpisodes*3+1) * gridSize
+	roomWidth := 4 * gridSize
+
+	qm := quakemap.NewQuakeMap(gridSize, -roomWidth/2, floorDepth+32)
+	qm.InfoPlayerStart.Angle = 0
+	qm.Wads = append(additionalWads, textureWad)
+	qm.WorldSpawn.AdditionalKeys["message"] = "Rise of the Triad"
+	if fgdFile != "" {
+		qm.WorldSpawn.AdditionalKeys["_tb_def"] = fmt.Sprintf("external:%s", fgdFile)
+	}
+
+	wallTexture := "WALL1"
+	floorTexture := "FLRCL1"
+	for episode := 1; episode <= NumEpisodes; episode++ {
+		if mapNumber, ok := startMaps[episode]; ok {
+			floorTexture = r.MapData[mapNumber-1].FloorTexture()
+			break
+		}
+	}
+
+	// floor, ceiling and outer walls
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(-gridSize, gridSize, 0,
+		roomLength+gridSize, -roomWidth-gridSize, floorDepth, floorTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(-gridSize, gridSize, floorDepth+roomHeight,
+		roomLength+gridSize, -roomWidth-gridSize, floorDepth+roomHeight+gridSize, floorTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(-gridSize, gridSize, floorDepth,
+		0, -roomWidth-gridSize, floorDepth+roomHeight, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(roomLength, gridSize, floorDepth,
+		roomLength+gridSize, -roomWidth-gridSize, floorDepth+roomHeight, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(0, gridSize, floorDepth,
+		roomLength, 0, floorDepth+roomHeight, wallTexture, scale, false))
+	qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(0, -roomWidth, floorDepth,
+		roomLength, -roomWidth-gridSize, floorDepth+roomHeight, wallTexture, scale, false))
+
+	for episode := 1; episode <= NumEpisodes; episode++ {
+		bayX := float64((episode-1)*3+1) * gridSize
+		mapNumber, ok := startMaps[episode]
+		if !ok {
+			// wall off episodes missing from the RTL file
+			qm.WorldSpawn.AddBrush(quakemap.BasicCuboid(bayX, -roomWidth/2, floorDepth,
+				bayX+2*gridSize, -roomWidth, floorDepth+roomHeight, wallTexture, scale, false))
+			continue
+		}
+		log.Printf("Episode %d starts at map%03d (%s)", episode, mapNumber, r.MapData[mapNumber-1].MapName())
+
+		entity := qm.SpawnEntity("trigger_changelevel", 0)
+		entity.AdditionalKeys["map"] = scheme.MapFileName(mapNumber)
+		entity.AddBrush(quakemap.BasicCuboid(bayX, -roomWidth*3/4, floorDepth,
+			bayX+2*gridSize, -roomWidth, floorDepth+roomHeight, "trigger", scale, false))
+
+		messageEntity := qm.SpawnEntity("trigger_multiple", 0)
+		messageEntity.AdditionalKeys["message"] = fmt.Sprintf("Episode %d: %s", episode, r.MapData[mapNumber-1].MapName())
+		messageEntity.AdditionalKeys["wait"] = "5"
+		messageEntity.AddBrush(quakemap.BasicCuboid(bayX, -roomWidth/2, floorDepth,
+			bayX+2*gridSize, -roomWidth*3/4, floorDepth+roomHeight, "trigger", scale, false))
+	}
+
+	target.FinishMap(qm)
+	return qm
+}
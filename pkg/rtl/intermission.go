@@ -0,0 +1,94 @@
+package rtl
+
+// Picking viewpoints for the end of level screen
+
+const (
+	// how far (in tiles) from the spawn or exit to look for a view
+	IntermissionSearchRadius = 8
+	// views shorter than this are not worth looking at
+	IntermissionMinDistance = 3
+)
+
+type IntermissionView struct {
+	X, Y      int
+	Direction WallDirection // one of the four cardinal directions
+	Distance  int           // open tiles in front of the view
+	score     int
+}
+
+// returns the number of open tiles from (x,y) in the direction, not
+// counting the starting tile
+func (r *RTLMapData) openDistance(x, y int, direction WallDirection) int {
+	deltaX, deltaY := direction.Delta()
+	distance := 0
+	for {
+		x += deltaX
+		y += deltaY
+		if x < 0 || x > 127 || y < 0 || y > 127 || r.ActorGrid[y][x].IsWall() {
+			return distance
+		}
+		distance++
+	}
+}
+
+// finds the best view within the search radius of (centerX,centerY):
+// standing with a wall behind, looking down the longest and widest
+// stretch of open floor
+func (r *RTLMapData) bestViewNear(centerX, centerY int, reachable *[128][128]bool) *IntermissionView {
+	var best *IntermissionView
+	for y := centerY - IntermissionSearchRadius; y <= centerY+IntermissionSearchRadius; y++ {
+		for x := centerX - IntermissionSearchRadius; x <= centerX+IntermissionSearchRadius; x++ {
+			if x < 0 || x > 127 || y < 0 || y > 127 || !reachable[y][x] || r.ActorGrid[y][x].IsWall() {
+				continue
+			}
+			for _, direction := range []WallDirection{DIR_East, DIR_North, DIR_West, DIR_South} {
+				behind := (direction + 4) % 8
+				if r.openDistance(x, y, behind) > 0 {
+					continue
+				}
+				distance := r.openDistance(x, y, direction)
+				if distance < IntermissionMinDistance {
+					continue
+				}
+
+				// how wide the room is halfway down the view
+				deltaX, deltaY := direction.Delta()
+				midX, midY := x+deltaX*distance/2, y+deltaY*distance/2
+				left, right := (direction+2)%8, (direction+6)%8
+				width := r.openDistance(midX, midY, left) + r.openDistance(midX, midY, right) + 1
+
+				score := distance*2 + width
+				if best == nil || score > best.score {
+					best = &IntermissionView{X: x, Y: y, Direction: direction, Distance: distance, score: score}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// returns up to one view near the spawn and one near each exit
+func (r *RTLMapData) IntermissionViews() []IntermissionView {
+	reachable := r.reachableTiles(false)
+
+	var views []IntermissionView
+	addView := func(view *IntermissionView) {
+		if view == nil {
+			return
+		}
+		for _, existing := range views {
+			if existing.X == view.X && existing.Y == view.Y && existing.Direction == view.Direction {
+				return
+			}
+		}
+		views = append(views, *view)
+	}
+
+	for _, point := range r.ExitPoints {
+		if !point.Secret {
+			addView(r.bestViewNear(point.X, point.Y, &reachable))
+		}
+	}
+	addView(r.bestViewNear(r.SpawnX, r.SpawnY, &reachable))
+	return views
+}
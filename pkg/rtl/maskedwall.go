@@ -18,11 +18,14 @@ func (m *MaskedWallFlags) String() string {
 
 type MaskedWallInfo struct {
 	// wall properties
-	Flags MaskedWallFlags
+	Flags MaskedWallFlags `json:"flags"`
 	// lump names for each wall component
-	Side, Middle, Above, Bottom string
+	Side   string `json:"side"`
+	Middle string `json:"middle"`
+	Above  string `json:"above"`
+	Bottom string `json:"bottom"`
 	// is it a switch?
-	IsSwitch bool
+	IsSwitch bool `json:"is_switch"`
 }
 
 const (
@@ -111,6 +114,14 @@ var MaskedWalls = map[uint16]MaskedWallInfo{
 	MW_Railing:    MaskedWallInfo{MWF_AbovePassable | MWF_MiddlePassable, "", "", "", "RAILING", false},
 }
 
+// shootable masked walls and what they look like once shot out
+var ShotOutMaskedWalls = map[uint16]uint16{
+	MW_MultiGlass1:         MW_ShotOutGlass1,
+	MW_MultiGlass2:         MW_ShotOutGlass2,
+	MW_MultiGlass3:         MW_ShotOutGlass3,
+	MW_SinglePaneShootable: MW_SinglePane,
+}
+
 var HMSK_Lumps = []string{
 	"HSWITCH1",
 	"HSWITCH2",
@@ -50,6 +50,13 @@ var (
 	DIR_Unknown   WallDirection = 8
 	ICONARROWS    int           = 72 // rt_actor.c:11010
 
+	// how many tiles a pushwall moves when pushed
+	PushWallDistance = 2
+
+	// damage dealt by moving walls to whatever blocks their path,
+	// ROTT crushes anything caught between a movewall and a wall
+	MoveWallDamage = 50
+
 	// rt_ted.c:2984
 	MoveWallSpriteIDs = map[uint16]MoveWallInfo{
 		// pushwalls
@@ -82,11 +89,41 @@ var (
 	}
 )
 
+// returns the grid deltas of moving a single tile in this direction
+func (w *WallDirection) Delta() (int, int) {
+	switch *w {
+	case DIR_East:
+		return 1, 0
+	case DIR_Northeast:
+		return 1, -1
+	case DIR_North:
+		return 0, -1
+	case DIR_Northwest:
+		return -1, -1
+	case DIR_West:
+		return -1, 0
+	case DIR_Southwest:
+		return -1, 1
+	case DIR_South:
+		return 0, 1
+	case DIR_Southeast:
+		return 1, 1
+	default:
+		panic("Unknown direction")
+	}
+}
+
 type MoveWallInfo struct {
 	Speed            int
 	InitialDirection WallDirection
 }
 
+// returns the func_train speed of the moving object, turbo movewalls
+// travel twice as fast as regular ones
+func (m *MoveWallInfo) TrainSpeed(scale float64) float64 {
+	return float64(m.Speed) * MovingObjectBaseSpeed * scale
+}
+
 type PathNode struct {
 	X         int
 	Y         int
@@ -108,42 +145,24 @@ func (r *RTLMapData) DetermineWallPath(actor *ActorInfo, pushWall bool) (WallPat
 		nodes = append(nodes, &p)
 	}
 	curX, curY := actor.X, actor.Y
-	deltaX, deltaY := 0, 0
 	pathType := PATH_Unknown
-	delta := 1
-	if pushWall {
-		delta = 2
-	}
+	pushDistance := 0
 	if moveWallInfo, ok := MoveWallSpriteIDs[actor.SpriteValue]; ok {
 		curDirection := moveWallInfo.InitialDirection
 		for pathType == PATH_Unknown {
-			switch curDirection {
-			case DIR_East:
-				deltaX = delta
-				deltaY = 0
-			case DIR_Northeast:
-				deltaX = delta
-				deltaY = -delta
-			case DIR_North:
-				deltaX = 0
-				deltaY = -delta
-			case DIR_Northwest:
-				deltaX = -delta
-				deltaY = -delta
-			case DIR_West:
-				deltaX = -delta
-				deltaY = 0
-			case DIR_Southwest:
-				deltaX = -delta
-				deltaY = delta
-			case DIR_South:
-				deltaX = 0
-				deltaY = delta
-			case DIR_Southeast:
-				deltaX = delta
-				deltaY = delta
-			default:
-				panic("Unknown direction")
+			deltaX, deltaY := curDirection.Delta()
+			if pushWall {
+				// pushwalls move up to PushWallDistance tiles, stopping
+				// short of anything in their way
+				nextX, nextY := curX+deltaX, curY+deltaY
+				if pushDistance == PushWallDistance ||
+					nextX > 127 || nextX < 0 || nextY > 127 || nextY < 0 ||
+					r.ActorGrid[nextY][nextX].IsWall() {
+					addNode(curX, curY, DIR_Unknown)
+					pathType = PATH_Terminal
+					continue
+				}
+				pushDistance++
 			}
 			curX += deltaX
 			curY += deltaY
@@ -189,10 +208,6 @@ func (r *RTLMapData) DetermineWallPath(actor *ActorInfo, pushWall bool) (WallPat
 				default:
 					continue
 				}
-			} else if pushWall {
-				addNode(curX, curY, DIR_Unknown)
-				pathType = PATH_Terminal
-				continue
 			}
 		}
 	}
@@ -202,3 +217,65 @@ func (r *RTLMapData) DetermineWallPath(actor *ActorInfo, pushWall bool) (WallPat
 		return pathType, nil, 0
 	}
 }
+
+// Traces the route of a patrolling enemy from its starting cell and
+// facing, turning at every arrow. Returns the corners of the route,
+// with the last node's Next pointing back into the loop. Dead ends
+// make the enemy walk back the way it came.
+func (r *RTLMapData) DeterminePatrolPath(actor *ActorInfo, direction WallDirection) (*PathNode, int) {
+	var nodes []*PathNode
+	markedNodes := make(map[string]*PathNode)
+
+	addNode := func(X int, Y int, direction WallDirection) *PathNode {
+		p := PathNode{X: X, Y: Y, Direction: direction, Next: nil}
+		if len(nodes) > 0 {
+			nodes[len(nodes)-1].Next = &p
+		}
+		nodes = append(nodes, &p)
+		return &p
+	}
+
+	curX, curY := actor.X, actor.Y
+	curDirection := direction
+	markedNodes[fmt.Sprintf("%d-%d-%d", curX, curY, curDirection)] = nil
+	for {
+		deltaX, deltaY := curDirection.Delta()
+		nextX, nextY := curX+deltaX, curY+deltaY
+		if nextX > 127 || nextX < 0 || nextY > 127 || nextY < 0 || r.ActorGrid[nextY][nextX].IsWall() {
+			// dead end, walk back to the start
+			if curX == actor.X && curY == actor.Y {
+				return nil, 0
+			}
+			forwardCount := len(nodes)
+			addNode(curX, curY, DIR_Unknown)
+			for i := forwardCount - 1; i >= 0; i-- {
+				addNode(nodes[i].X, nodes[i].Y, nodes[i].Direction)
+			}
+			start := addNode(actor.X, actor.Y, direction)
+			start.Next = nodes[0]
+			return nodes[0], len(nodes)
+		}
+		curX, curY = nextX, nextY
+
+		spriteVal := r.ActorGrid[curY][curX].SpriteValue
+		if spriteVal >= uint16(ICONARROWS) && spriteVal <= uint16(ICONARROWS)+7 {
+			curDirection = WallDirection(spriteVal - uint16(ICONARROWS))
+		}
+
+		// back on an arrow already passed (or the starting cell facing
+		// the same way), the route loops from here
+		markerTag := fmt.Sprintf("%d-%d-%d", curX, curY, curDirection)
+		if prevNode, ok := markedNodes[markerTag]; ok {
+			if prevNode == nil {
+				prevNode = addNode(actor.X, actor.Y, direction)
+				prevNode.Next = nodes[0]
+			} else {
+				nodes[len(nodes)-1].Next = prevNode
+			}
+			return nodes[0], len(nodes)
+		}
+		if spriteVal >= uint16(ICONARROWS) && spriteVal <= uint16(ICONARROWS)+7 {
+			markedNodes[markerTag] = addNode(curX, curY, curDirection)
+		}
+	}
+}
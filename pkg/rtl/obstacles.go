@@ -0,0 +1,215 @@
+package rtl
+
+// Crushers and spikes
+
+import (
+	"fmt"
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
+const (
+	// rt_ted.c (SetupInanimateActors), the "down" variants sit one
+	// row (18 sprites) below the "up" ones in the map editor
+	SpikesUp      uint16 = 412
+	CrusherUp     uint16 = 413
+	SpikesDown    uint16 = 430
+	CrusherDown   uint16 = 431
+	ObstacleSpeed        = 4.0 // multiplier of MovingObjectBaseSpeed
+
+	// approximate cycle timings (in seconds) of rt_actor.c's spear and
+	// crushing column states, ROTT runs at 35 tics per second
+	SpikesRetractedTime float64 = 2.0
+	SpikesRaisedTime    float64 = 1.0
+	CrusherWaitTime     float64 = 0.5
+
+	SpikesDamage  = 20
+	CrusherDamage = 50
+
+	obstacleTexture = "metal1_6"
+)
+
+// returns true for the sprite values of crushers and spikes
+func IsObstacle(spriteValue uint16) bool {
+	switch spriteValue {
+	case SpikesUp, CrusherUp, SpikesDown, CrusherDown:
+		return true
+	default:
+		return false
+	}
+}
+
+// builds a looping 2-corner func_train path and links it to a touchplate
+// if the obstacle is touchplate triggered. (x1, y1) is the minimum
+// corner of the entity's brushes, which func_trains move along the path.
+func addObstacleTrain(entity *quakemap.Entity, name string, x1, y1, restZ, activeZ, restWait, activeWait float64,
+	actor *ActorInfo, r *RTLMapData, q *quakemap.QuakeMap) {
+
+	restCornerName := fmt.Sprintf("%s_rest", name)
+	activeCornerName := fmt.Sprintf("%s_active", name)
+
+	restCorner := q.SpawnEntity("path_corner", 0)
+	restCorner.OriginX = x1
+	restCorner.OriginY = y1
+	restCorner.OriginZ = restZ
+	restCorner.AdditionalKeys["targetname"] = restCornerName
+	restCorner.AdditionalKeys["target"] = activeCornerName
+	restCorner.AdditionalKeys["wait"] = fmt.Sprintf("%.02f", restWait)
+
+	activeCorner := q.SpawnEntity("path_corner", 0)
+	activeCorner.OriginX = x1
+	activeCorner.OriginY = y1
+	activeCorner.OriginZ = activeZ
+	activeCorner.AdditionalKeys["targetname"] = activeCornerName
+	activeCorner.AdditionalKeys["target"] = restCornerName
+	activeCorner.AdditionalKeys["wait"] = fmt.Sprintf("%.02f", activeWait)
+
+	entity.AdditionalKeys["target"] = restCornerName
+
+	if trigger, touchplateX, touchplateY := r.ActorTrigger(actor, TRIGGER_Obstacle); trigger != nil {
+		// func_train waits to be triggered when it has a targetname
+		entity.AdditionalKeys["targetname"] = name
+
+		SpawnTriggerRelay(q, x1, y1, restZ, touchplateX, touchplateY, name, trigger.Delay)
+	}
+}
+
+// makes the obstacle a func_bob moving between its rest and active
+// positions on its own. func_bob cannot wait for a touchplate, so
+// touchplate triggered obstacles fall back to a func_train.
+func addObstacleBob(entity *quakemap.Entity, name string, x1, y1, restZ, activeZ, restWait, activeWait float64,
+	actor *ActorInfo, r *RTLMapData, q *quakemap.QuakeMap) {
+
+	if trigger, _, _ := r.ActorTrigger(actor, TRIGGER_Obstacle); trigger != nil {
+		entity.ClassName = "func_train"
+		addObstacleTrain(entity, name, x1, y1, restZ, activeZ, restWait, activeWait, actor, r, q)
+		return
+	}
+
+	if activeZ > restZ {
+		entity.AdditionalKeys["angle"] = "-1"
+		entity.AdditionalKeys["height"] = fmt.Sprintf("%.02f", activeZ-restZ)
+	} else {
+		entity.AdditionalKeys["angle"] = "-2"
+		entity.AdditionalKeys["height"] = fmt.Sprintf("%.02f", restZ-activeZ)
+	}
+	// one full cycle
+	entity.AdditionalKeys["count"] = fmt.Sprintf("%.02f", restWait+activeWait)
+}
+
+// adds the obstacle's movement for the kind of entity it is
+func addObstacleMovement(entity *quakemap.Entity, name string, x1, y1, restZ, activeZ, restWait, activeWait float64,
+	actor *ActorInfo, r *RTLMapData, q *quakemap.QuakeMap) {
+
+	if entity.ClassName == "func_bob" {
+		addObstacleBob(entity, name, x1, y1, restZ, activeZ, restWait, activeWait, actor, r, q)
+	} else {
+		addObstacleTrain(entity, name, x1, y1, restZ, activeZ, restWait, activeWait, actor, r, q)
+	}
+}
+
+// adds spikes that rise out of the floor (or drop from the ceiling)
+// and retract on ROTT's cycle
+func AddSpikes(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
+
+	actor := &r.ActorGrid[y][x]
+	entityName := item.EntityName(target)
+
+	floorDepth := gridSizeZ
+	ceilingZ := floorDepth + float64(r.FloorHeight())*gridSizeZ
+	spikeHeight := gridSizeZ / 2.0
+	spikeWidth := gridSizeX / 8.0
+	x1 := float64(x) * gridSizeX
+	y1 := float64(y+1) * -gridSizeY
+
+	// 3x3 grid of spikes centered in the tile, built in place so only
+	// their height needs adjusting
+	entity := q.SpawnEntity(entityName, 0)
+	AddDefaultEntityKeys(entity, actor)
+	for i := 1; i <= 3; i++ {
+		for j := 1; j <= 3; j++ {
+			cx := x1 + float64(i)*(gridSizeX/4.0)
+			cy := y1 + float64(j)*(gridSizeY/4.0)
+			entity.AddBrush(quakemap.BasicCuboid(
+				cx-(spikeWidth/2.0), cy-(spikeWidth/2.0), 0,
+				cx+(spikeWidth/2.0), cy+(spikeWidth/2.0), spikeHeight,
+				obstacleTexture, gridSizeX/64.0, false))
+		}
+	}
+
+	var restZ, activeZ, hurtZ1, hurtZ2 float64
+	if actor.SpriteValue == SpikesDown {
+		restZ = ceilingZ
+		activeZ = ceilingZ - spikeHeight
+		hurtZ1 = activeZ
+		hurtZ2 = ceilingZ
+	} else {
+		restZ = floorDepth - spikeHeight
+		activeZ = floorDepth
+		hurtZ1 = floorDepth
+		hurtZ2 = floorDepth + spikeHeight
+	}
+	entity.Translate(0, 0, restZ)
+	entity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", ObstacleSpeed*MovingObjectBaseSpeed*(gridSizeX/64.0))
+	entity.AdditionalKeys["dmg"] = fmt.Sprintf("%d", SpikesDamage)
+
+	// the path follows the corner of the first spike
+	name := fmt.Sprintf("spikes_%d_%d", x, y)
+	cornerX := x1 + (gridSizeX / 4.0) - (spikeWidth / 2.0)
+	cornerY := y1 + (gridSizeY / 4.0) - (spikeWidth / 2.0)
+	addObstacleMovement(entity, name, cornerX, cornerY, restZ, activeZ,
+		SpikesRetractedTime, SpikesRaisedTime, actor, r, q)
+
+	// trigger_hurt cannot be toggled in vanilla Quake, so spread the
+	// damage over the time the spikes are actually raised
+	dutyCycle := SpikesRaisedTime / (SpikesRaisedTime + SpikesRetractedTime)
+	hurtEntity := q.SpawnEntity("trigger_hurt", 0)
+	hurtEntity.AddBrush(quakemap.BasicCuboid(
+		x1, y1, hurtZ1,
+		x1+gridSizeX, y1+gridSizeY, hurtZ2,
+		"trigger", gridSizeX/64.0, false))
+	hurtEntity.AdditionalKeys["dmg"] = fmt.Sprintf("%d", int(float64(SpikesDamage)*dutyCycle)+1)
+	AddDefaultEntityKeys(hurtEntity, actor)
+	return entity
+}
+
+// adds crushing columns that slam into the ceiling (or floor)
+func AddCrusher(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
+
+	actor := &r.ActorGrid[y][x]
+	entityName := item.EntityName(target)
+
+	floorDepth := gridSizeZ
+	columnHeight := float64(r.FloorHeight()) * gridSizeZ
+	margin := gridSizeX / 16.0
+	x1 := float64(x)*gridSizeX + margin
+	y1 := float64(y+1)*-gridSizeY + margin
+
+	entity := q.SpawnEntity(entityName, 0)
+	AddDefaultEntityKeys(entity, actor)
+	entity.AddBrush(quakemap.BasicCuboid(
+		x1, y1, 0,
+		x1+gridSizeX-(margin*2.0), y1+gridSizeY-(margin*2.0), columnHeight,
+		obstacleTexture, gridSizeX/64.0, false))
+
+	var restZ, activeZ float64
+	if actor.SpriteValue == CrusherDown {
+		// hides in the ceiling, crushes against the floor
+		restZ = floorDepth + columnHeight
+		activeZ = floorDepth
+	} else {
+		// hides in the floor, crushes against the ceiling
+		restZ = floorDepth - columnHeight
+		activeZ = floorDepth
+	}
+	entity.Translate(0, 0, restZ)
+	entity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", ObstacleSpeed*MovingObjectBaseSpeed*(gridSizeX/64.0))
+	// func_train damages whatever blocks it
+	entity.AdditionalKeys["dmg"] = fmt.Sprintf("%d", CrusherDamage)
+
+	name := fmt.Sprintf("crusher_%d_%d", x, y)
+	addObstacleMovement(entity, name, x1, y1, restZ, activeZ,
+		CrusherWaitTime, CrusherWaitTime, actor, r, q)
+	return entity
+}
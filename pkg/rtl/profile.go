@@ -0,0 +1,234 @@
+package rtl
+
+// Target game profiles: entity class names, spawnflags and engine
+// quirks of the games maps are converted for
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
+// New targets implement TargetProfile and add themselves with
+// RegisterTargetProfile, usually by embedding QuakeProfile (or
+// BaseProfile for the defaults alone) and only overriding what differs.
+type TargetProfile interface {
+	// selects the target and keys its entries in the mapping tables
+	Name() string
+
+	// default replacement entity of an item, an empty name skips it
+	ItemEntityName(item *ItemInfo) string
+	// height and Z offset of items placed on the floor
+	ItemPlacement(item *ItemInfo) (float64, float64)
+	// default replacement entities of an enemy
+	EnemyEntityNames(enemy *EnemyConversionInfo) []string
+
+	// spawnflags keeping an entity off the easy, normal and hard
+	// skill levels
+	NotOnSkillFlags() (int, int, int)
+	// spawnflags of a monster waiting for the player
+	AmbushSpawnFlag() int
+
+	// returns false if clip brushes in other entities are ignored, in
+	// which case they need an entity of their own
+	ClipInEntities() bool
+	// spawns an entity blocking movement with the brush
+	SpawnClip(qm *quakemap.QuakeMap, clipBrush quakemap.Brush) *quakemap.Entity
+
+	// key item entities, handed out to locks in order
+	KeyEntityNames() []string
+	// Z offset of key items
+	KeyZOffset() float64
+	// makes the door need the key at the index of KeyEntityNames
+	LockDoor(doorEntity *quakemap.Entity, keyIndex int)
+
+	// returns true if func_breakable is supported
+	HasBreakables() bool
+	// sets up a func_breakable made of glass
+	SetupBreakable(entity *quakemap.Entity)
+	// returns the angle of a fireball shooter facing the angle
+	FireballAngle(angle int) int
+
+	// reworks the finished map for the target, e.g. for entities it
+	// does not have
+	FinishMap(qm *quakemap.QuakeMap)
+}
+
+var targetProfiles = make(map[string]TargetProfile)
+
+func RegisterTargetProfile(profile TargetProfile) {
+	targetProfiles[profile.Name()] = profile
+}
+
+func GetTargetProfile(name string) (TargetProfile, error) {
+	profile, ok := targetProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown target %q (expected one of %s)", name, strings.Join(TargetProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// returns the names of all registered targets
+func TargetProfileNames() []string {
+	var names []string
+	for name := range targetProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterTargetProfile(QuakeProfile{})
+	RegisterTargetProfile(DuskProfile{})
+	if err := duskMapping.Apply(); err != nil {
+		panic(fmt.Sprintf("invalid Dusk mapping: %v", err))
+	}
+}
+
+// Defaults shared by the targets, the methods it leaves out have no
+// sensible default
+type BaseProfile struct{}
+
+// the Quake skill flags, used by most Quake engine games
+func (BaseProfile) NotOnSkillFlags() (int, int, int) {
+	return quakemap.SPAWNFLAG_NotOnEasy, quakemap.SPAWNFLAG_NotOnNormal, quakemap.SPAWNFLAG_NotOnHard
+}
+
+func (BaseProfile) AmbushSpawnFlag() int {
+	return quakemap.SPAWNFLAG_Ambush
+}
+
+// keys are placed at their item's origin
+func (BaseProfile) KeyZOffset() float64 {
+	return 0.0
+}
+
+// no func_breakable, shootable glass is converted to plain walls
+func (BaseProfile) HasBreakables() bool {
+	return false
+}
+
+// breakables need no keys besides their brushes
+func (BaseProfile) SetupBreakable(entity *quakemap.Entity) {
+}
+
+// fireball shooters fire towards their angle
+func (BaseProfile) FireballAngle(angle int) int {
+	return angle
+}
+
+// the map is written as converted
+func (BaseProfile) FinishMap(qm *quakemap.QuakeMap) {
+}
+
+// vanilla Quake (id1)
+type QuakeProfile struct {
+	BaseProfile
+}
+
+func (QuakeProfile) Name() string {
+	return "quake"
+}
+
+func (QuakeProfile) ItemEntityName(item *ItemInfo) string {
+	return item.QuakeEntityName
+}
+
+func (QuakeProfile) ItemPlacement(item *ItemInfo) (float64, float64) {
+	return item.QuakeHeight, item.QuakeZOffset
+}
+
+func (QuakeProfile) EnemyEntityNames(enemy *EnemyConversionInfo) []string {
+	return enemy.QuakeEnemyNames
+}
+
+func (QuakeProfile) ClipInEntities() bool {
+	return true
+}
+
+func (QuakeProfile) SpawnClip(qm *quakemap.QuakeMap, clipBrush quakemap.Brush) *quakemap.Entity {
+	clipEntity := qm.SpawnEntity("func_detail", 0)
+	clipEntity.AddBrush(clipBrush)
+	return clipEntity
+}
+
+func (QuakeProfile) KeyEntityNames() []string {
+	return []string{"item_key1", "item_key2"}
+}
+
+func (QuakeProfile) LockDoor(doorEntity *quakemap.Entity, keyIndex int) {
+	// silver key (16), then gold key (8)
+	doorEntity.SpawnFlags |= (2 - keyIndex) * 8
+}
+
+// Dusk SDK
+type DuskProfile struct {
+	BaseProfile
+}
+
+// keys of Dusk entities that the other targets' entities don't take
+var duskMapping = MappingConfig{
+	Items: map[string]ItemConfig{
+		// rotating blades
+		"0xae": {Targets: map[string]*ItemTargetConfig{"dusk": {Keys: map[string]string{"damage": "10.0", "frequency": "0.8"}}}},
+	},
+}
+
+func (DuskProfile) Name() string {
+	return "dusk"
+}
+
+func (DuskProfile) ItemEntityName(item *ItemInfo) string {
+	return item.DuskEntityName
+}
+
+func (DuskProfile) ItemPlacement(item *ItemInfo) (float64, float64) {
+	return item.DuskHeight, item.DuskZOffset
+}
+
+func (DuskProfile) EnemyEntityNames(enemy *EnemyConversionInfo) []string {
+	return enemy.DuskEnemyNames
+}
+
+// HACK/FIXME: Dusk SDK ignores clip textures
+func (DuskProfile) ClipInEntities() bool {
+	return false
+}
+
+// FIXME when clip textures are no longer busted in Dusk,
+// see https://discord.com/channels/240195284695646209/586508960128040961/799031954728419348
+func (DuskProfile) SpawnClip(qm *quakemap.QuakeMap, clipBrush quakemap.Brush) *quakemap.Entity {
+	clipEntity := qm.SpawnEntity("func_wall", 0)
+	clipEntity.AdditionalKeys["rendermode"] = "1"
+	clipEntity.AdditionalKeys["renderamt"] = "0"
+	// just pick an arbitrary texture besides "clip"
+	clipBrush.SetTextureForAllPlanes("FLRCL1")
+	clipEntity.AddBrush(clipBrush)
+	return clipEntity
+}
+
+func (DuskProfile) KeyEntityNames() []string {
+	return []string{"key_red_key", "key_blue_key", "key_yellow_key"}
+}
+
+// FIXME when/if dusk SDK fixes keys being placed a lot lower than the
+// entity's origin
+func (DuskProfile) KeyZOffset() float64 {
+	return 600.0
+}
+
+func (DuskProfile) LockDoor(doorEntity *quakemap.Entity, keyIndex int) {
+	doorEntity.AdditionalKeys["key"] = fmt.Sprintf("%d", keyIndex+1)
+}
+
+func (DuskProfile) HasBreakables() bool {
+	return true
+}
+
+// Dusk's fireball shooters fire in the opposite direction
+func (DuskProfile) FireballAngle(angle int) int {
+	return (angle + 180) % 360
+}
@@ -0,0 +1,353 @@
+package rtl
+
+// Quake 2, which reads its textures from .wal files and lacks a few of
+// the Quake entities the conversion relies on
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
+const (
+	// directory under textures/ the .wal files are written to
+	Quake2TextureDir = "rott"
+
+	quake2ClipTexture    = "e1u1/clip"
+	quake2TriggerTexture = "e1u1/trigger"
+
+	// seconds between target_blaster shots
+	Quake2BlasterWait = 2.0
+)
+
+func init() {
+	RegisterTargetProfile(Quake2Profile{})
+	if err := quake2Mapping.Apply(); err != nil {
+		panic(fmt.Sprintf("invalid Quake 2 mapping: %v", err))
+	}
+}
+
+// Quake items without a Quake 2 counterpart of the same name
+var quake2ItemNames = map[string]string{
+	"weapon_nailgun":                "weapon_machinegun",
+	"weapon_lightning":              "weapon_hyperblaster",
+	"item_armor2":                   "item_armor_combat",
+	"item_artifact_invulnerability": "item_invulnerability",
+	"item_artifact_super_damage":    "item_quad",
+	"item_artifact_invisibility":    "item_silencer",
+	"misc_explobox2":                "misc_explobox",
+	"trap_shooter":                  "target_blaster",
+}
+
+// Quake monsters by their closest Quake 2 match, used for enemies
+// missing from the mapping below
+var quake2MonsterNames = map[string]string{
+	"monster_army":          "monster_soldier_light",
+	"monster_dog":           "monster_parasite",
+	"monster_enforcer":      "monster_soldier",
+	"monster_knight":        "monster_berserk",
+	"monster_hell_knight":   "monster_gladiator",
+	"monster_ogre":          "monster_gunner",
+	"monster_ogre_marksman": "monster_gunner",
+	"monster_wizard":        "monster_flyer",
+	"monster_shalrath":      "monster_chick",
+	"monster_shambler":      "monster_tank",
+	"monster_demon1":        "monster_mutant",
+}
+
+// Builds on QuakeProfile, FinishMap replaces what Quake 2 does not have
+type Quake2Profile struct {
+	QuakeProfile
+}
+
+func (Quake2Profile) Name() string {
+	return "quake2"
+}
+
+func (Quake2Profile) ItemEntityName(item *ItemInfo) string {
+	if name, ok := quake2ItemNames[item.QuakeEntityName]; ok {
+		return name
+	}
+	return item.QuakeEntityName
+}
+
+func (Quake2Profile) EnemyEntityNames(enemy *EnemyConversionInfo) []string {
+	var names []string
+	for _, name := range enemy.QuakeEnemyNames {
+		if q2Name, ok := quake2MonsterNames[name]; ok {
+			name = q2Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (Quake2Profile) KeyEntityNames() []string {
+	return []string{"key_blue_key", "key_red_key", "key_pass", "key_data_cd"}
+}
+
+// Quake 2 doors cannot check for keys, a trigger_key in front of the
+// door does it instead. trigger_key takes the key away, so it opens
+// every door of that lock for good.
+func (profile Quake2Profile) LockDoor(doorEntity *quakemap.Entity, keyIndex int) {
+	qm := doorEntity.Map
+	doorName := fmt.Sprintf("keydoors_%d", keyIndex)
+	keyName := fmt.Sprintf("keylock_%d", keyIndex)
+	doorEntity.AdditionalKeys["targetname"] = doorName
+	doorEntity.AdditionalKeys["wait"] = "-1"
+
+	minX, minY, minZ, maxX, maxY, maxZ := doorEntity.Bounds()
+	margin := (maxZ - minZ) / 2.0
+
+	hasKeyEntity := false
+	for _, entity := range qm.Entities {
+		if entity.ClassName == "trigger_key" && entity.AdditionalKeys["targetname"] == keyName {
+			hasKeyEntity = true
+		}
+	}
+	if !hasKeyEntity {
+		keyEntity := qm.SpawnEntity("trigger_key", 0)
+		keyEntity.OriginX = (minX + maxX) / 2.0
+		keyEntity.OriginY = (minY + maxY) / 2.0
+		keyEntity.OriginZ = (minZ + maxZ) / 2.0
+		keyEntity.AdditionalKeys["targetname"] = keyName
+		keyEntity.AdditionalKeys["target"] = doorName
+		keyEntity.AdditionalKeys["item"] = profile.KeyEntityNames()[keyIndex]
+	}
+
+	touchEntity := qm.SpawnEntity("trigger_multiple", 0)
+	touchEntity.AdditionalKeys["target"] = keyName
+	touchEntity.AddBrush(quakemap.BasicCuboid(
+		minX-margin, minY-margin, minZ,
+		maxX+margin, maxY+margin, maxZ,
+		"trigger", 1.0, false))
+}
+
+func (Quake2Profile) HasBreakables() bool {
+	return true
+}
+
+func (Quake2Profile) SetupBreakable(entity *quakemap.Entity) {
+	entity.ClassName = "func_explosive"
+	entity.AdditionalKeys["health"] = "1"
+	entity.AdditionalKeys["dmg"] = "0"
+}
+
+func (Quake2Profile) FinishMap(qm *quakemap.QuakeMap) {
+	qm.SurfaceFields = true
+
+	setQuake2Surfaces(qm.WorldSpawn)
+	for _, entity := range qm.Entities {
+		setQuake2Surfaces(entity)
+	}
+
+	type changeLevel struct {
+		minX, minY, minZ, maxX, maxY, maxZ float64
+		targetName                         string
+	}
+	var changeLevels []changeLevel
+	var gatedTeleports []*quakemap.Entity
+	destinations := make(map[string]*quakemap.Entity)
+
+	var entities []*quakemap.Entity
+	for i, entity := range qm.Entities {
+		_, hasTargetName := entity.AdditionalKeys["targetname"]
+		switch entity.ClassName {
+		case "func_detail":
+			if hasTargetName {
+				break
+			}
+			// detail brushes are part of the world
+			moveToWorld(entity, qm, quakemap.CONTENTS_Solid|quakemap.CONTENTS_Detail)
+			continue
+		case "func_illusionary":
+			if hasTargetName {
+				break
+			}
+			moveToWorld(entity, qm, quakemap.CONTENTS_Mist|quakemap.CONTENTS_Detail)
+			continue
+		case "trigger_changelevel":
+			targetName := fmt.Sprintf("changelevel_%d", i)
+			changeLevelEntity := quakemap.NewEntity(0, "target_changelevel", qm)
+			changeLevelEntity.AdditionalKeys["targetname"] = targetName
+			changeLevelEntity.AdditionalKeys["map"] = entity.AdditionalKeys["map"]
+			delete(entity.AdditionalKeys, "map")
+			minX, minY, minZ, maxX, maxY, maxZ := entity.Bounds()
+			changeLevelEntity.OriginX = (minX + maxX) / 2.0
+			changeLevelEntity.OriginY = (minY + maxY) / 2.0
+			changeLevelEntity.OriginZ = (minZ + maxZ) / 2.0
+			entities = append(entities, changeLevelEntity)
+			changeLevels = append(changeLevels, changeLevel{minX, minY, minZ, maxX, maxY, maxZ, targetName})
+
+			entity.ClassName = "trigger_multiple"
+			entity.AdditionalKeys["target"] = targetName
+		case "trigger_secret":
+			targetName := fmt.Sprintf("secret_%d", i)
+			secretEntity := quakemap.NewEntity(0, "target_secret", qm)
+			secretEntity.AdditionalKeys["targetname"] = targetName
+			if message, ok := entity.AdditionalKeys["message"]; ok {
+				secretEntity.AdditionalKeys["message"] = message
+				delete(entity.AdditionalKeys, "message")
+			}
+			minX, minY, minZ, maxX, maxY, maxZ := entity.Bounds()
+			secretEntity.OriginX = (minX + maxX) / 2.0
+			secretEntity.OriginY = (minY + maxY) / 2.0
+			secretEntity.OriginZ = (minZ + maxZ) / 2.0
+			entities = append(entities, secretEntity)
+
+			entity.ClassName = "trigger_once"
+			entity.AdditionalKeys["target"] = targetName
+		case "target_blaster":
+			if hasTargetName {
+				break
+			}
+			// target_blaster only fires when used, keep it firing like
+			// trap_shooter
+			targetName := fmt.Sprintf("blaster_%d", i)
+			entity.AdditionalKeys["targetname"] = targetName
+			if damage, ok := entity.AdditionalKeys["damage"]; ok {
+				entity.AdditionalKeys["dmg"] = damage
+				delete(entity.AdditionalKeys, "damage")
+			}
+			timerEntity := quakemap.NewEntity(1, "func_timer", qm) // start on
+			timerEntity.OriginX, timerEntity.OriginY, timerEntity.OriginZ = entity.OriginX, entity.OriginY, entity.OriginZ
+			timerEntity.AdditionalKeys["target"] = targetName
+			timerEntity.AdditionalKeys["wait"] = fmt.Sprintf("%.02f", Quake2BlasterWait)
+			entities = append(entities, timerEntity)
+		case "info_intermission":
+			entity.ClassName = "info_player_intermission"
+			entity.AdditionalKeys["angles"] = entity.AdditionalKeys["mangle"]
+			delete(entity.AdditionalKeys, "mangle")
+		case "info_teleport_destination":
+			entity.ClassName = "misc_teleporter_dest"
+			destinations[entity.AdditionalKeys["targetname"]] = entity
+		case "trigger_teleport":
+			if hasTargetName {
+				gatedTeleports = append(gatedTeleports, entity)
+				break
+			}
+			// teleporter pads are point entities with a trigger of
+			// their own
+			minX, minY, minZ, maxX, maxY, _ := entity.Bounds()
+			entity.ClassName = "misc_teleporter"
+			entity.OriginX = (minX + maxX) / 2.0
+			entity.OriginY = (minY + maxY) / 2.0
+			entity.OriginZ = minZ
+			entity.SpawnFlags = 0
+			entity.Brushes = nil
+		}
+		entities = append(entities, entity)
+	}
+
+	// teleporters cannot be turned on, so whatever fires one changes
+	// the level at its destination directly
+	for _, entity := range gatedTeleports {
+		entity.ClassName = "trigger_relay"
+		entity.SpawnFlags = 0
+		entity.Brushes = nil
+		dest, ok := destinations[entity.AdditionalKeys["target"]]
+		delete(entity.AdditionalKeys, "target")
+		if !ok {
+			log.Printf("Teleporter %s has no destination, ignoring it", entity.AdditionalKeys["targetname"])
+			continue
+		}
+		entity.OriginX, entity.OriginY, entity.OriginZ = dest.OriginX, dest.OriginY, dest.OriginZ
+		for _, level := range changeLevels {
+			if dest.OriginX >= level.minX && dest.OriginX <= level.maxX &&
+				dest.OriginY >= level.minY && dest.OriginY <= level.maxY &&
+				dest.OriginZ >= level.minZ && dest.OriginZ <= level.maxZ {
+				entity.AdditionalKeys["target"] = level.targetName
+			}
+		}
+		if _, ok := entity.AdditionalKeys["target"]; !ok {
+			log.Printf("Teleporter %s does not lead to a level change, ignoring it", entity.AdditionalKeys["targetname"])
+		}
+	}
+
+	qm.Entities = entities
+}
+
+// points the entity's textures at the .wal files and sets the surface
+// flags of clip, trigger and masked textures
+func setQuake2Surfaces(entity *quakemap.Entity) {
+	for i := range entity.Brushes {
+		planes := entity.Brushes[i].Planes
+		for j := range planes {
+			plane := &planes[j]
+			if strings.Contains(plane.Texture, "/") {
+				// brushes may share their planes
+				continue
+			}
+			switch {
+			case plane.Texture == "clip":
+				plane.Texture = quake2ClipTexture
+				plane.Contents = quakemap.CONTENTS_PlayerClip | quakemap.CONTENTS_MonsterClip
+				plane.SurfaceFlags = quakemap.SURF_NoDraw
+			case plane.Texture == "trigger":
+				plane.Texture = quake2TriggerTexture
+				plane.SurfaceFlags = quakemap.SURF_NoDraw
+			case strings.HasPrefix(plane.Texture, "{"):
+				// no alpha testing, see-through is as close as it gets
+				plane.Texture = Quake2TextureName(plane.Texture)
+				plane.Contents = quakemap.CONTENTS_Window
+				plane.SurfaceFlags = quakemap.SURF_Trans66
+			default:
+				plane.Texture = Quake2TextureName(plane.Texture)
+			}
+		}
+	}
+}
+
+// moves the entity's brushes into worldspawn with the contents added
+func moveToWorld(entity *quakemap.Entity, qm *quakemap.QuakeMap, contents int) {
+	for _, brush := range entity.Brushes {
+		for j := range brush.Planes {
+			plane := &brush.Planes[j]
+			if plane.Contents&(quakemap.CONTENTS_PlayerClip|quakemap.CONTENTS_MonsterClip) != 0 {
+				plane.Contents |= quakemap.CONTENTS_Detail
+			} else {
+				plane.Contents = contents
+			}
+		}
+		qm.WorldSpawn.AddBrush(brush)
+	}
+}
+
+// returns the name of a converted texture's .wal file, relative to the
+// textures directory and without extension
+func Quake2TextureName(texture string) string {
+	return Quake2TextureDir + "/" + strings.ToLower(texture)
+}
+
+// items and enemies without an entry here are translated from their
+// Quake entities
+var quake2Mapping = MappingConfig{
+	Items: map[string]ItemConfig{
+		// one-up, three-up
+		"0x28": {Targets: map[string]*ItemTargetConfig{"quake2": {Name: "item_health_mega", KeepCallback: true}}},
+		"0x29": {Targets: map[string]*ItemTargetConfig{"quake2": {Name: "item_health_mega", KeepCallback: true}}},
+		// trampolines
+		"0xc1": {Targets: map[string]*ItemTargetConfig{"quake2": {Name: "trigger_push", KeepCallback: true}}},
+	},
+	Enemies: map[string]EnemyConfig{
+		"low_guard":        {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_soldier_light"}}}},
+		"sneaky_low_guard": {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_soldier_light"}}}},
+		"high_guard":       {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_soldier"}}}},
+		"overpatrol_guard": {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_soldier_ss"}}}},
+		"strike_guard":     {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_infantry"}}}},
+		"triad_enforcer":   {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_gunner"}}}},
+		"lightning_guard":  {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_gladiator"}}}},
+		"monk":             {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_berserk"}}}},
+		"fire_monk":        {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_chick"}}}},
+		"robo_guard":       {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_floater"}}}},
+		"ballistikraft":    {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_tank"}}}},
+		"gun_emplacement":  {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_soldier_ss"}}}},
+		"4_way_gun":        {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_soldier_ss"}}}},
+		"general_darian":   {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_tank_commander"}}}},
+		"sebastian_krist":  {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_supertank"}}}},
+		"nme":              {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_boss2"}}}},
+		"el_oscuro":        {Targets: map[string][]EntityChoice{"quake2": {{Name: "monster_makron"}}}},
+	},
+}
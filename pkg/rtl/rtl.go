@@ -15,6 +15,10 @@ var (
 	// any tile value in the first plane above this number is part of an area
 	AreaTileMin uint16 = 107
 	NumAreas    uint16 = 47
+	// rt_ted.h (IsWindow)
+	WindowTile uint16 = 13
+	// wall switch, flips from WALL73 to WALL74 when pressed
+	WallSwitchTile uint16 = 46
 )
 
 type RTLHeader struct {
@@ -134,6 +138,7 @@ type DoorInfo struct {
 
 type ExitPoint struct {
 	X, Y, DestMap int
+	Secret        bool
 }
 
 func (actor *ActorInfo) IsWall() bool {
@@ -146,6 +151,7 @@ func (actor *ActorInfo) IsWall() bool {
 		WALL_Platform,
 		WALL_Window,
 		WALL_PushWall,
+		WALL_Switch,
 		WALL_Door:
 		return true
 	default:
@@ -153,6 +159,19 @@ func (actor *ActorInfo) IsWall() bool {
 	}
 }
 
+// returns true for walls the player presses to fire their touchplate
+// actions, including hi switches on masked walls
+func (actor *ActorInfo) IsSwitch() bool {
+	switch actor.Type {
+	case WALL_Switch:
+		return true
+	case WALL_MaskedWall:
+		return MaskedWalls[actor.Tile].IsSwitch
+	default:
+		return false
+	}
+}
+
 // html -- true for HTML map gen (return static image equivalent texture name)
 //         false for Quake map gen (return Quake animated texture name)
 func (actor *ActorInfo) WallTileToTextureName(html bool) string {
@@ -199,12 +218,12 @@ func (actor *ActorInfo) WallTileToTextureName(html bool) string {
 		default:
 			panic(fmt.Sprintf("Illegal door number %d at (%d, %d)", tileId, actor.X, actor.Y))
 		}
-	} else if actor.Type == WALL_Regular || actor.Type == WALL_ThinWall {
+	} else if actor.Type == WALL_Regular || actor.Type == WALL_ThinWall || actor.Type == WALL_Switch || actor.Type == WALL_Window {
 		if tileId >= 1 && tileId <= 32 {
 			return fmt.Sprintf("WALL%d", tileId)
 		} else if tileId >= 36 && tileId <= 45 {
 			return fmt.Sprintf("WALL%d", tileId-3)
-		} else if tileId == 46 {
+		} else if tileId == WallSwitchTile {
 			return "WALL73"
 		} else if tileId == 47 || tileId == 48 {
 			return exitLumps[tileId-47]
@@ -241,6 +260,18 @@ func (actor *ActorInfo) WallTileToTextureName(html bool) string {
 	}
 }
 
+// returns true if the wall lump is used for see-through windows, which
+// need a masked texture as well
+func IsWindowLump(lumpName string) bool {
+	window := ActorInfo{Tile: WindowTile, Type: WALL_Window}
+	return window.WallTileToTextureName(false) == lumpName
+}
+
+// texture shown on the buttons of a wall switch
+func (actor *ActorInfo) SwitchTextureName() string {
+	return SwitchTextureName(actor.WallTileToTextureName(false))
+}
+
 type RTLMapData struct {
 	Header      RTLMapHeader
 	WallPlane   [128][128]uint16
@@ -249,6 +280,7 @@ type RTLMapData struct {
 	ActorGrid   [128][128]ActorInfo
 	Doors       []Door
 	ExitPoints  []ExitPoint
+	SecretAreas []SecretArea
 
 	// derived from wall plane
 	FloorNumber    int // 0xb4 - 0xc3
@@ -268,6 +300,11 @@ type RTLMapData struct {
 	// derived from info plane
 	SongNumber int
 
+	// conversion options
+	KeyStrategy     KeyStrategy
+	MapNameScheme   MapNameScheme
+	BossHealthScale float64 // 0 leaves boss health alone
+
 	rtl *RTL
 }
 
@@ -282,6 +319,13 @@ func (r *RTLMapData) CeilingTexture() string {
 	return ""
 }
 
+// returns true if the tile is part of the map's floor, floor tiles
+// carry their area number in the wall plane
+func (r *RTLMapData) IsFloor(x, y int) bool {
+	tileId := r.WallPlane[y][x]
+	return tileId >= AreaTileMin && tileId <= AreaTileMin+NumAreas
+}
+
 func (r *RTLMapData) FloorHeight() int {
 	if r.Height >= 90 && r.Height <= 97 {
 		return r.Height - 89
@@ -325,7 +369,7 @@ func (r *RTLMapData) ThinWallDirection(x, y int) (WallDirection, int, int) {
 
 	if x > 0 {
 		wallType := r.ActorGrid[y][x-1].Type
-		if wallType == WALL_Regular {
+		if wallType == WALL_Regular || wallType == WALL_Switch {
 			adjacentCountX += 2
 		} else if wallType == WALL_MaskedWall {
 			adjacentCountX += 4
@@ -335,7 +379,7 @@ func (r *RTLMapData) ThinWallDirection(x, y int) (WallDirection, int, int) {
 	}
 	if x < 127 {
 		wallType := r.ActorGrid[y][x+1].Type
-		if wallType == WALL_Regular {
+		if wallType == WALL_Regular || wallType == WALL_Switch {
 			adjacentCountX += 2
 		} else if wallType == WALL_MaskedWall {
 			adjacentCountX += 4
@@ -345,7 +389,7 @@ func (r *RTLMapData) ThinWallDirection(x, y int) (WallDirection, int, int) {
 	}
 	if y > 0 {
 		wallType := r.ActorGrid[y-1][x].Type
-		if wallType == WALL_Regular {
+		if wallType == WALL_Regular || wallType == WALL_Switch {
 			adjacentCountY += 2
 		} else if wallType == WALL_MaskedWall {
 			adjacentCountY += 4
@@ -355,7 +399,7 @@ func (r *RTLMapData) ThinWallDirection(x, y int) (WallDirection, int, int) {
 	}
 	if y < 127 {
 		wallType := r.ActorGrid[y+1][x].Type
-		if wallType == WALL_Regular {
+		if wallType == WALL_Regular || wallType == WALL_Switch {
 			adjacentCountY += 2
 		} else if wallType == WALL_MaskedWall {
 			adjacentCountY += 4
@@ -423,11 +467,14 @@ func NewRTL(rfile io.ReadSeeker) (*RTL, error) {
 		r.MapData[i].determineMovingWalls()
 		r.MapData[i].renderSpriteGrid()
 		r.MapData[i].determineExits()
+		r.MapData[i].determineDoors()
 		r.MapData[i].determineGADs()
 		if r.MapData[i].MapName() != "" {
 			r.MapData[i].processUndefinedHeights()
 			r.MapData[i].processEnemies()
 		}
+		r.MapData[i].determineTriggers()
+		r.MapData[i].determineSecrets()
 
 		for j := 0; j < 128; j++ {
 			if r.MapData[i].InfoPlane[0][j]&0xFF00 == 0xBA00 {
@@ -507,6 +554,21 @@ func (r *RTLMapData) renderWallGrid() {
 				continue
 			}
 
+			if tileId == WindowTile {
+				// see-through window
+				r.ActorGrid[y][x].Tile = tileId
+				r.ActorGrid[y][x].MapFlags |= WALLFLAGS_Static
+				r.ActorGrid[y][x].Type = WALL_Window
+				continue
+			}
+
+			if tileId == WallSwitchTile {
+				r.ActorGrid[y][x].Tile = tileId
+				r.ActorGrid[y][x].MapFlags |= WALLFLAGS_Static
+				r.ActorGrid[y][x].Type = WALL_Switch
+				continue
+			}
+
 			if tileId <= 32 || (tileId >= 36 && tileId <= 43) {
 				// static wall
 				r.ActorGrid[y][x].Tile = tileId
@@ -699,11 +761,12 @@ func (r *RTLMapData) determineExits() {
 			// rt_ted.c:1531
 			exitMarker := int(r.ActorGrid[y][x].InfoValue >> 8)
 			mapNumber := int(r.ActorGrid[y][x].InfoValue & 0x00ff)
-			if exitMarker == 0xe2 || exitMarker == 0xe4 {
+			if exitMarker == 0xe2 || exitMarker == SecretExitMarker {
 				r.ExitPoints = append(r.ExitPoints, ExitPoint{
 					X:       x,
 					Y:       y,
 					DestMap: mapNumber + 1,
+					Secret:  exitMarker == SecretExitMarker,
 				})
 			}
 		}
@@ -898,7 +961,7 @@ func (r *RTLMapData) DumpMapToHtmlFile(w io.Writer) error {
 			wallInfo := r.ActorGrid[y][x]
 			img := wallInfo.WallTileToTextureName(true)
 			switch wallInfo.Type {
-			case WALL_Regular, WALL_ThinWall:
+			case WALL_Regular, WALL_ThinWall, WALL_Switch, WALL_Window:
 				img = "wall/" + img
 			case WALL_AnimatedWall:
 				img = "anim/" + img
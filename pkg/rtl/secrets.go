@@ -0,0 +1,119 @@
+package rtl
+
+// Secret areas: parts of the map that can only be reached through a
+// pushwall, along with secret exits
+
+// rt_ted.c:1531
+const SecretExitMarker = 0xe4
+
+type SecretArea struct {
+	Tiles      []*ActorInfo
+	SecretExit bool
+}
+
+// returns true for walls moved by the player pushing them, as opposed
+// to ones moving on their own or triggered by a touchplate
+func (actor *ActorInfo) IsPushWall() bool {
+	return actor.MapFlags&WALLFLAGS_Moving != 0 && actor.InfoValue == 0 && actor.SpriteValue < 256
+}
+
+// returns true if the player can eventually walk through the tile,
+// pushwalls only when pushThrough is set
+func (actor *ActorInfo) isWalkable(pushThrough bool) bool {
+	if actor.IsPushWall() {
+		return pushThrough
+	}
+	switch actor.Type {
+	case WALL_Door, WALL_MaskedWall, WALL_Platform, WALL_ThinWall:
+		return true
+	}
+	return !actor.IsWall() || actor.MapFlags&WALLFLAGS_Moving != 0
+}
+
+// flood fills the map from the player start and elevators
+func (r *RTLMapData) reachableTiles(pushThrough bool) [128][128]bool {
+	var reached [128][128]bool
+	var queue []*ActorInfo
+
+	visit := func(x, y int) {
+		if x < 0 || x > 127 || y < 0 || y > 127 || reached[y][x] {
+			return
+		}
+		if !r.ActorGrid[y][x].isWalkable(pushThrough) {
+			return
+		}
+		reached[y][x] = true
+		queue = append(queue, &r.ActorGrid[y][x])
+	}
+
+	visit(r.SpawnX, r.SpawnY)
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			// elevators take the player to otherwise unconnected
+			// parts of the map, see LinkElevators
+			if r.WallPlane[y][x] == 0x66 {
+				visit(x, y)
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		actor := queue[0]
+		queue = queue[1:]
+		visit(actor.X+1, actor.Y)
+		visit(actor.X-1, actor.Y)
+		visit(actor.X, actor.Y+1)
+		visit(actor.X, actor.Y-1)
+	}
+	return reached
+}
+
+// groups tiles only reachable by opening pushwalls into secret areas,
+// each secret exit is an area of its own
+func (r *RTLMapData) determineSecrets() {
+	reachable := r.reachableTiles(false)
+	pushReachable := r.reachableTiles(true)
+
+	var secret [128][128]bool
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			secret[y][x] = pushReachable[y][x] && !reachable[y][x]
+		}
+	}
+
+	for _, exit := range r.ExitPoints {
+		if !exit.Secret {
+			continue
+		}
+		secret[exit.Y][exit.X] = false
+		r.SecretAreas = append(r.SecretAreas, SecretArea{
+			Tiles:      []*ActorInfo{&r.ActorGrid[exit.Y][exit.X]},
+			SecretExit: true,
+		})
+	}
+
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if !secret[y][x] {
+				continue
+			}
+			area := SecretArea{}
+			queue := []*ActorInfo{&r.ActorGrid[y][x]}
+			secret[y][x] = false
+			for len(queue) > 0 {
+				actor := queue[0]
+				queue = queue[1:]
+				area.Tiles = append(area.Tiles, actor)
+				for _, delta := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+					nx, ny := actor.X+delta[0], actor.Y+delta[1]
+					if nx < 0 || nx > 127 || ny < 0 || ny > 127 || !secret[ny][nx] {
+						continue
+					}
+					secret[ny][nx] = false
+					queue = append(queue, &r.ActorGrid[ny][nx])
+				}
+			}
+			r.SecretAreas = append(r.SecretAreas, area)
+		}
+	}
+}
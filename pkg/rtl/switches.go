@@ -0,0 +1,79 @@
+package rtl
+
+// Switch graphics and their Quake "+0"/"+a" texture pairs
+
+import (
+	"sort"
+	"strings"
+)
+
+// ROTT switch graphics mapped to the graphic shown once flipped.
+// Both states get their own pair since switches can start out on.
+// Masked ones are listed for completeness but can't flip, see
+// isMaskedSwitchLump.
+var SwitchTexturePairs = map[string]string{
+	// wall switch (WallSwitchTile)
+	"WALL73": "WALL74",
+	"WALL74": "WALL73",
+	// hi switches, the typo is intentional, the same typo is in
+	// DARKWAR.WAD
+	"HSWITCH3": "HSWTICH4",
+	"HSWTICH4": "HSWITCH3",
+	// the remaining hi switch graphics come in off/on pairs as well
+	"HSWITCH5": "HSWITCH6",
+	"HSWITCH6": "HSWITCH5",
+	"HSWITCH7": "HSWITCH8",
+	"HSWITCH8": "HSWITCH7",
+	"HSWTCH9":  "HSWTCH10",
+	"HSWTCH10": "HSWTCH9",
+}
+
+// Hi switches are masked graphics. Quake only animates textures
+// starting with "+" and only makes ones starting with "{" see-through,
+// a texture can't be both, so masked switches keep their transparency
+// and don't flip when pressed.
+func isMaskedSwitchLump(lumpName string) bool {
+	for _, maskedLumpName := range HMSK_Lumps {
+		if maskedLumpName == lumpName {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the Quake texture name for a switch graphic, func_buttons
+// flip to the "+a" alternate of it when pressed
+func SwitchTextureName(lumpName string) string {
+	if isMaskedSwitchLump(lumpName) {
+		return "{" + lumpName
+	}
+	if _, ok := SwitchTexturePairs[lumpName]; ok {
+		return "+0" + strings.ToLower(lumpName)
+	}
+	return lumpName
+}
+
+// returns the texture names a switch graphic needs to be exported as:
+// the "+0" frame of its own pair and the "+a" frame of its opposite
+func SwitchTextureLumps(lumpName string) []string {
+	if isMaskedSwitchLump(lumpName) {
+		return nil
+	}
+	// sorted so the WAD comes out the same every time
+	var offLumps []string
+	for offLump := range SwitchTexturePairs {
+		offLumps = append(offLumps, offLump)
+	}
+	sort.Strings(offLumps)
+
+	var lumpNames []string
+	for _, offLump := range offLumps {
+		if offLump == lumpName {
+			lumpNames = append(lumpNames, "+0"+strings.ToLower(offLump))
+		}
+		if SwitchTexturePairs[offLump] == lumpName {
+			lumpNames = append(lumpNames, "+a"+strings.ToLower(offLump))
+		}
+	}
+	return lumpNames
+}
@@ -8,8 +8,10 @@ import (
 	"math"
 )
 
+// Spawns the entities of an item, returns the one standing for the item
+// itself (which gets the item's mapping keys), nil if there is none
 type EntityAdderCallback func(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool)
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity
 
 type ItemInfo struct {
 	TileId          uint16 // is it represented by a tile (can be 0)
@@ -21,188 +23,255 @@ type ItemInfo struct {
 	QuakeZOffset    float64
 	DuskZOffset     float64
 	PlaceOnFloor    bool
-	AddCallback     EntityAdderCallback // callback function (takes precedence over replacement entity names)
+	AddCallback     EntityAdderCallback          // callback function (takes precedence over replacement entity names)
+	Targets         map[string]*ItemTargetConfig // overrides keyed by target name (see LoadMappingConfig)
 }
 
-// TODO: this needs to be configuration-driven
+// returns the overrides of the target, if any
+func (item *ItemInfo) targetConfig(target TargetProfile) *ItemTargetConfig {
+	return item.Targets[target.Name()]
+}
+
+// returns true if the item is spawned by its callback for the target
+// rather than a replacement entity
+func (item *ItemInfo) UsesCallback(target TargetProfile) bool {
+	if item.AddCallback == nil {
+		return false
+	}
+	// replacement entities take precedence over built-in ones
+	config := item.targetConfig(target)
+	return config == nil || config.Name == "" || config.KeepCallback
+}
+
+// returns the replacement entity of the item for the target, an empty
+// name if it has none
+func (item *ItemInfo) EntityName(target TargetProfile) string {
+	if config := item.targetConfig(target); config != nil && config.Name != "" {
+		return config.Name
+	}
+	return target.ItemEntityName(item)
+}
+
+// returns the height and Z offset of the item placed on the floor
+func (item *ItemInfo) Placement(target TargetProfile) (float64, float64) {
+	height, zOffset := target.ItemPlacement(item)
+	if config := item.targetConfig(target); config != nil {
+		if config.Height != nil {
+			height = *config.Height
+		}
+		if config.ZOffset != nil {
+			zOffset = *config.ZOffset
+		}
+	}
+	return height, zOffset
+}
+
+// returns the additional entity keys of the item for the target
+func (item *ItemInfo) EntityKeys(target TargetProfile) map[string]string {
+	if config := item.targetConfig(target); config != nil {
+		return config.Keys
+	}
+	return nil
+}
 
+const (
+	LightPost uint16 = 0x3f
+
+	QuakeGravity = 800.0 // default sv_gravity
+)
+
+// defaults, see LoadMappingConfig for overriding them
 var Items = map[uint16]ItemInfo{
 	// weapons
 
 	// bat
 	0x2e: ItemInfo{
-		0, 0x2e, "weapon_nailgun", "weapon_sword", 0, 0, 0, 0, false, nil,
+		0, 0x2e, "weapon_nailgun", "weapon_sword", 0, 0, 0, 0, false, nil, nil,
 	},
 	// knife
 	0x2f: ItemInfo{
-		0, 0x2f, "weapon_nailgun", "weapon_crossbow", 0, 0, 0, 0, false, nil,
+		0, 0x2f, "weapon_nailgun", "weapon_crossbow", 0, 0, 0, 0, false, nil, nil,
 	},
 	// double-pistol
 	0x30: ItemInfo{
-		0, 0x30, "weapon_supershotgun", "weapon_pistol", 0, 0, 0, 0, false, nil,
+		0, 0x30, "weapon_supershotgun", "weapon_pistol", 0, 0, 0, 0, false, nil, nil,
 	},
 	// mp40
 	0x31: ItemInfo{
-		0, 0x31, "weapon_nailgun", "weapon_mg", 0, 0, 0, 0, false, nil,
+		0, 0x31, "weapon_nailgun", "weapon_mg", 0, 0, 0, 0, false, nil, nil,
 	},
 	// bazooka
 	0x32: ItemInfo{
-		0, 0x32, "weapon_rocketlauncher", "weapon_supershotgun", 0, 0, 0, 0, false, nil,
+		0, 0x32, "weapon_rocketlauncher", "weapon_supershotgun", 0, 0, 0, 0, false, nil, nil,
 	},
 	// firebomb
 	0x33: ItemInfo{
-		0, 0x33, "weapon_rocketlauncher", "weapon_riveter", 0, 0, 0, 0, false, nil,
+		0, 0x33, "weapon_rocketlauncher", "weapon_riveter", 0, 0, 0, 0, false, nil, nil,
 	},
 	// heatseaker
 	0x34: ItemInfo{
-		0, 0x34, "weapon_rocketlauncher", "weapon_rifle", 0, 0, 0, 0, false, nil,
+		0, 0x34, "weapon_rocketlauncher", "weapon_rifle", 0, 0, 0, 0, false, nil, nil,
 	},
 	// drunk missle
 	0x35: ItemInfo{
-		0, 0x35, "weapon_grenadelauncher", "weapon_mortar", 0, 0, 0, 0, false, nil,
+		0, 0x35, "weapon_grenadelauncher", "weapon_mortar", 0, 0, 0, 0, false, nil, nil,
 	},
 	// flamewall
 	0x36: ItemInfo{
-		0, 0x36, "weapon_supershotgun", "weapon_shotgun", 0, 0, 0, 0, false, nil,
+		0, 0x36, "weapon_supershotgun", "weapon_shotgun", 0, 0, 0, 0, false, nil, nil,
 	},
 	// split missle
 	0x37: ItemInfo{
-		0, 0x37, "weapon_supershotgun", "weapon_supershotgun", 0, 0, 0, 0, false, nil,
+		0, 0x37, "weapon_supershotgun", "weapon_supershotgun", 0, 0, 0, 0, false, nil, nil,
 	},
 	// dark staff
 	0x38: ItemInfo{
-		0, 0x38, "weapon_lightning", "prop_soap", 0, 0, 0, 0, false, nil,
+		0, 0x38, "weapon_lightning", "prop_soap", 0, 0, 0, 0, false, nil, nil,
 	},
 
 	// pickups
 
 	// silver ankh coin
 	0x39: ItemInfo{
-		0, 0x39, "", "pickup_coin", 0, 0, 0, 0, false, AddAnkhCoin,
+		0, 0x39, "", "pickup_coin", 0, 0, 0, 0, false, AddAnkhCoin, nil,
 	},
 	// gold ankh coin
 	0x3a: ItemInfo{
-		0, 0x3a, "", "pickup_coin", 0, 0, 0, 0, false, AddAnkhCoin,
+		0, 0x3a, "", "pickup_coin", 0, 0, 0, 0, false, AddAnkhCoin, nil,
 	},
 	// reeded gold ankh coin
 	0x3b: ItemInfo{
-		0, 0x3b, "", "pickup_coin", 0, 0, 0, 0, false, AddAnkhCoin,
+		0, 0x3b, "", "pickup_coin", 0, 0, 0, 0, false, AddAnkhCoin, nil,
 	},
 	// ringed pink ankh
 	0x3c: ItemInfo{
-		0, 0x3c, "", "pickup_diamond", 0, 0, 0, 0, false, AddAnkhCoin,
+		0, 0x3c, "", "pickup_diamond", 0, 0, 0, 0, false, AddAnkhCoin, nil,
 	},
 
 	// one-up
 	0x28: ItemInfo{
-		0, 0x28, "", "pickup_health_hallowed", 0, 0, 0, 0, false, AddAnkhCoin,
+		0, 0x28, "", "pickup_health_hallowed", 0, 0, 0, 0, false, AddAnkhCoin, nil,
 	},
 	// three-up
 	0x29: ItemInfo{
-		0, 0x28, "", "pickup_health_hallowed", 0, 0, 0, 0, false, AddAnkhCoin,
+		0, 0x28, "", "pickup_health_hallowed", 0, 0, 0, 0, false, AddAnkhCoin, nil,
 	},
 
 	// priest porridge
 	0x24: ItemInfo{
-		0, 0x24, "item_health", "pickup_health_small", 0, 0, 0, 0, false, nil,
+		0, 0x24, "item_health", "pickup_health_small", 0, 0, 0, 0, false, nil, nil,
 	},
 	// monk meal
 	0x25: ItemInfo{
-		0, 0x25, "item_health", "pickup_health_medium", 0, 0, 0, 0, false, nil,
+		0, 0x25, "item_health", "pickup_health_medium", 0, 0, 0, 0, false, nil, nil,
 	},
 	// small monk crystal
 	0x26: ItemInfo{
-		0, 0x26, "item_health", "pickup_health_medium", 0, 0, 0, 0, false, nil,
+		0, 0x26, "item_health", "pickup_health_medium", 0, 0, 0, 0, false, nil, nil,
 	},
 	// large monk crystal
 	0x27: ItemInfo{
-		0, 0x27, "item_health", "pickup_health_large", 0, 0, 0, 0, false, nil,
+		0, 0x27, "item_health", "pickup_health_large", 0, 0, 0, 0, false, nil, nil,
 	},
 
 	// powerups
 
 	// god mode
 	0xfc: ItemInfo{
-		0, 0xfc, "item_artifact_invulnerability", "item_artifact_invulnerability", 0, 0, 0, 0, false, nil,
+		0, 0xfc, "item_artifact_invulnerability", "item_artifact_invulnerability", 0, 0, 0, 0, false, nil, nil,
 	},
 	// mercury mode (nothing similar to it in Quake, so wing it)
 	0xfe: ItemInfo{
-		0, 0xfe, "item_artifact_super_damage", "pickup_climber", 0, 0, 0, 0, false, nil,
+		0, 0xfe, "item_artifact_super_damage", "pickup_climber", 0, 0, 0, 0, false, nil, nil,
 	},
 	// elasto mode (also nothing similar to it in Quake)
 	0x104: ItemInfo{
-		0, 0x104, "item_artifact_invisibility", "prop_bottle", 0, 0, 0, 0, false, nil,
+		0, 0x104, "item_artifact_invisibility", "prop_bottle", 0, 0, 0, 0, false, nil, nil,
 	},
 	// shrooms mode (also nothing similar to it in Quake)
 	0x105: ItemInfo{
-		0, 0x105, "item_artifact_invisibility", "prop_bottle", 0, 0, 0, 0, false, nil,
+		0, 0x105, "item_artifact_invisibility", "prop_bottle", 0, 0, 0, 0, false, nil, nil,
 	},
 
 	// armor
 	0x10e: ItemInfo{
-		0, 0x10e, "item_armor2", "item_armor2", 0, 0, 0, 0, false, nil,
+		0, 0x10e, "item_armor2", "item_armor2", 0, 0, 0, 0, false, nil, nil,
 	},
 
 	// misc
 
 	// trampolines
 	0xc1: ItemInfo{
-		0, 0x5a, "object_jump_pad", "object_jump_pad", 0, 0, 0, 0, false, AddTrampoline,
+		0, 0x5a, "", "object_jump_pad", 0, 0, 0, 0, false, AddTrampoline, nil,
 	},
 	// rotating blades
 	0xae: ItemInfo{
-		0, 0xae, "", "object_blades", 0, 0, 0, 0, false, AddSpinningBlades,
+		0, 0xae, "", "object_blades", 0, 0, 0, 0, false, AddSpinningBlades, nil,
+	},
+	// spikes
+	SpikesUp: ItemInfo{
+		0, SpikesUp, "func_train", "func_train", 0, 0, 0, 0, false, AddSpikes, nil,
+	},
+	SpikesDown: ItemInfo{
+		0, SpikesDown, "func_train", "func_train", 0, 0, 0, 0, false, AddSpikes, nil,
+	},
+	// crushing columns
+	CrusherUp: ItemInfo{
+		0, CrusherUp, "func_train", "func_train", 0, 0, 0, 0, false, AddCrusher, nil,
+	},
+	CrusherDown: ItemInfo{
+		0, CrusherDown, "func_train", "func_train", 0, 0, 0, 0, false, AddCrusher, nil,
 	},
 	// columns
 	0xf8: ItemInfo{
-		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn,
+		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn, nil,
 	},
 	0xf9: ItemInfo{
-		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn,
+		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn, nil,
 	},
 	0xfa: ItemInfo{
-		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn,
+		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn, nil,
 	},
 	0xfb: ItemInfo{
-		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn,
+		0, 0x141, "func_detail", "func_detail", 0, 0, 0, 0, false, AddColumn, nil,
 	},
 	// push columns
 	0x141: ItemInfo{
-		0, 0x141, "func_train", "func_train", 0, 0, 0, 0, false, AddColumn,
+		0, 0x141, "func_train", "func_train", 0, 0, 0, 0, false, AddColumn, nil,
 	},
 	0x165: ItemInfo{
-		0, 0x141, "func_train", "func_train", 0, 0, 0, 0, false, AddColumn,
+		0, 0x141, "func_train", "func_train", 0, 0, 0, 0, false, AddColumn, nil,
 	},
 	// exploding barrels
 	0x10d: ItemInfo{
-		0, 0x10d, "misc_explobox", "prop_barrel_exploding_2", 64, 46, 0, 0, true, nil,
+		0, 0x10d, "misc_explobox", "prop_barrel_exploding_2", 64, 46, 0, 0, true, nil, nil,
 	},
 	0x3e: ItemInfo{
-		0, 0x10d, "misc_explobox", "prop_barrel_exploding_2", 64, 46, 0, 0, true, nil,
+		0, 0x10d, "misc_explobox", "prop_barrel_exploding_2", 64, 46, 0, 0, true, nil, nil,
 	},
 	// exploding box
 	0x3d: ItemInfo{
-		0, 0x10d, "misc_explobox2", "misc_explobox2", 32, 32, 0, 0, true, nil,
+		0, 0x10d, "misc_explobox2", "misc_explobox2", 32, 32, 0, 0, true, nil, nil,
 	},
 	// light post
-	0x3f: ItemInfo{
-		0, 0x3f, "", "object_light_post_1", 0, 72, 0, 20, true, nil,
+	LightPost: ItemInfo{
+		0, 0x3f, "", "object_light_post_1", 0, 72, 0, 20, true, nil, nil,
 	},
 	// flamethrowers
 	0x186: ItemInfo{
-		0, 0x186, "", "object_anomaly_fire", 0, 0, 0, 0, false, AddFlamethrower,
+		0, 0x186, "", "object_anomaly_fire", 0, 0, 0, 0, false, AddFlamethrower, nil,
 	},
 	// fireball shooter
 	0x0b: ItemInfo{
-		0x0b, 0, "trap_shooter", "object_fireball_shooter", 0, 0, 0, 0, false, AddFireballShooter,
+		0x0b, 0, "trap_shooter", "object_fireball_shooter", 0, 0, 0, 0, false, AddFireballShooter, nil,
 	},
 	// firepit
 	0x40: ItemInfo{
-		0x40, 0, "", "object_campfire", 0, 40, 0, -12, true, nil,
+		0x40, 0, "", "object_campfire", 0, 40, 0, -12, true, nil, nil,
 	},
 	// vase
 	0x10a: ItemInfo{
-		0x10a, 0, "", "prop_vase", 0, 16, 0, 0, true, nil,
+		0x10a, 0, "", "prop_vase", 0, 16, 0, 0, true, nil, nil,
 	},
 }
 
@@ -221,14 +290,15 @@ func (r *RTLMapData) PlatformItemHeight(infoVal uint16) int {
 
 // adds ankh coins
 func AddAnkhCoin(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool) {
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
 
 	actor := r.ActorGrid[y][x]
-	if !dusk {
-		return
+	entityName := item.EntityName(target)
+	if entityName == "" {
+		return nil
 	}
 
-	entity := q.SpawnEntity(item.DuskEntityName, 0)
+	entity := q.SpawnEntity(entityName, 0)
 	AddDefaultEntityKeys(entity, &actor)
 	entity.OriginX = (float64(x) + 0.5) * gridSizeX
 	entity.OriginY = (float64(y) + 0.5) * -gridSizeY
@@ -243,11 +313,12 @@ func AddAnkhCoin(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ f
 	case actor.InfoValue == 0x0c:
 		entity.OriginZ = (float64(r.FloorHeight()+1) * gridSizeZ) - ((float64(actor.ItemHeight+32) * gridSizeZ) / 64.0)
 	}
+	return entity
 }
 
 // adds column or push column
 func AddColumn(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool) {
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
 
 	actor := &r.ActorGrid[y][x]
 	entityType := "func_detail"
@@ -349,8 +420,7 @@ func AddColumn(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ flo
 		endCorner.OriginY = initialCorner.OriginY - (float64(touchdy) * gridSizeY)
 		endCorner.OriginZ = gridSizeZ
 		endCorner.AdditionalKeys["targetname"] = tgtPathEnd
-		endCorner.AdditionalKeys["target"] = "idontexist"
-		endCorner.AdditionalKeys["wait"] = "-1"
+		StopTrainAtCorner(endCorner)
 
 		pushEntityRelay := q.SpawnEntity("trigger_relay", 0)
 		pushEntityRelay.OriginX = (float64(x) + 0.5) * gridSizeZ
@@ -372,17 +442,22 @@ func AddColumn(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ flo
 		}
 
 	}
+	return entity
 }
 
 // adds trampolines right on the floor
 func AddTrampoline(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool) {
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
 
-	if !dusk {
+	entityName := item.EntityName(target)
+	if entityName == "" {
 		// just rocket jump i guess
-		return
+		return nil
 	}
-	entity := q.SpawnEntity(item.DuskEntityName, 0)
+	if entityName == "trigger_push" {
+		return AddJumpPad(x, y, gridSizeX, gridSizeY, gridSizeZ, r, q)
+	}
+	entity := q.SpawnEntity(entityName, 0)
 	entity.OriginX = float64(x)*gridSizeX + (gridSizeX / 2.0)
 	entity.OriginY = float64(y)*-gridSizeY - (gridSizeY / 2.0)
 	entity.OriginZ = gridSizeZ
@@ -390,47 +465,69 @@ func AddTrampoline(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ
 	// this logarithmic formula is a ballpark factor that just Seems Right(tm)
 	jumpAmount := math.Log10(float64(r.FloorHeight())+0.5) * ((gridSizeZ / 64) / 2)
 	entity.AdditionalKeys["amount"] = fmt.Sprintf("%02f", jumpAmount)
+	return entity
+}
+
+// adds a thin trigger_push on the floor launching the player up to
+// about the ceiling
+func AddJumpPad(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
+	r *RTLMapData, q *quakemap.QuakeMap) *quakemap.Entity {
+
+	x1 := float64(x) * gridSizeX
+	y1 := float64(y+1) * -gridSizeY
+	entity := q.SpawnEntity("trigger_push", 0)
+	entity.AddBrush(quakemap.BasicCuboid(
+		x1, y1, gridSizeZ,
+		x1+gridSizeX, y1+gridSizeY, gridSizeZ+(gridSizeZ/4.0),
+		"trigger", gridSizeX/64.0, false))
+	AddDefaultEntityKeys(entity, &r.ActorGrid[y][x])
+
+	jumpHeight := float64(r.FloorHeight()-1) * gridSizeZ
+	if jumpHeight < gridSizeZ {
+		jumpHeight = gridSizeZ
+	}
+	// push straight up, trigger_push multiplies its speed by 10
+	entity.AdditionalKeys["angle"] = "-1"
+	entity.AdditionalKeys["speed"] = fmt.Sprintf("%.02f", math.Sqrt(2.0*QuakeGravity*jumpHeight)/10.0)
+	return entity
 }
 
 // adds static spinning blades centered in the grid
 func AddSpinningBlades(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool) {
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
 
-	if !dusk {
-		// not supported for quake
-		return
+	entityName := item.EntityName(target)
+	if entityName == "" {
+		// not supported by the target
+		return nil
 	}
-	entityName := item.DuskEntityName
 	entity := q.SpawnEntity(entityName, 0)
 	entity.OriginX = float64(x)*gridSizeX + (gridSizeX / 2.0)
 	entity.OriginY = float64(y)*-gridSizeY - (gridSizeY / 2.0)
 	entity.OriginZ = gridSizeZ * 1.5
-	entity.AdditionalKeys["damage"] = "10.0"
-	entity.AdditionalKeys["frequency"] = "0.8"
+	return entity
 }
 
 // adds static flamethrowers on the bottom facing up
 func AddFlamethrower(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool) {
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
 
-	if !dusk {
-		// not supported for quake
-		return
+	entityName := item.EntityName(target)
+	if entityName == "" {
+		// not supported by the target
+		return nil
 	}
-	entityName := item.DuskEntityName
 	entity := q.SpawnEntity(entityName, 0)
 	entity.OriginX = float64(x)*gridSizeX + (gridSizeX / 2.0)
 	entity.OriginY = float64(y)*-gridSizeY - (gridSizeY / 2.0)
 	entity.OriginZ = gridSizeZ
+	return entity
 }
 
 func AddFireballShooter(x int, y int, gridSizeX float64, gridSizeY float64, gridSizeZ float64,
-	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, dusk bool) {
+	item *ItemInfo, r *RTLMapData, q *quakemap.QuakeMap, target TargetProfile) *quakemap.Entity {
 
-	entityName := item.QuakeEntityName
-	if dusk {
-		entityName = item.DuskEntityName
-	}
+	entityName := item.EntityName(target)
 	actor := r.ActorGrid[y][x]
 
 	var xoffset, yoffset float64
@@ -455,10 +552,7 @@ func AddFireballShooter(x int, y int, gridSizeX float64, gridSizeY float64, grid
 		yoffset = -(gridSizeY / 2.0)
 	}
 
-	if dusk {
-		// Dusk's fireball shooters fire in the opposite direction
-		angle = (angle + 180) % 360
-	}
+	angle = target.FireballAngle(angle)
 
 	entity := q.SpawnEntity(entityName, 0)
 	entity.OriginX = float64(x)*gridSizeX + (gridSizeX / 2) + xoffset
@@ -466,4 +560,5 @@ func AddFireballShooter(x int, y int, gridSizeX float64, gridSizeY float64, grid
 	entity.OriginZ = gridSizeZ * 1.5
 	entity.AdditionalKeys["angle"] = fmt.Sprintf("%d", angle)
 	entity.AdditionalKeys["damage"] = "30"
+	return entity
 }
@@ -1,20 +1,177 @@
 package rtl
 
+// Touchplate triggers and the actions they fire
+
+import (
+	"fmt"
+	"gitlab.com/camtap/rott2quake/pkg/quakemap"
+)
+
 type TriggerAction int
 
 const (
 	TRIGGER_WallPush TriggerAction = iota
+	TRIGGER_Obstacle
+	TRIGGER_DoorOpen
+	TRIGGER_GAD
+	TRIGGER_LightToggle
+	TRIGGER_EnemyActivate
+)
+
+const (
+	// ROTT only refires a touchplate once the player steps off of it,
+	// approximate that with a trigger_multiple wait
+	TouchplateRetriggerWait float64 = 2.0
 )
 
+func (t TriggerAction) String() string {
+	switch t {
+	case TRIGGER_WallPush:
+		return "WallPush"
+	case TRIGGER_Obstacle:
+		return "Obstacle"
+	case TRIGGER_DoorOpen:
+		return "DoorOpen"
+	case TRIGGER_GAD:
+		return "GAD"
+	case TRIGGER_LightToggle:
+		return "LightToggle"
+	case TRIGGER_EnemyActivate:
+		return "EnemyActivate"
+	default:
+		return "Unknown"
+	}
+}
+
+// returns true if firing the action again has any effect, in which
+// case the touchplate needs to be a trigger_multiple. Quake movers
+// cannot be stopped once started so only lights qualify.
+func (t TriggerAction) Repeatable() bool {
+	return t == TRIGGER_LightToggle
+}
+
 type MapTrigger struct {
 	Actor  *ActorInfo
 	Action TriggerAction
+	Delay  float64 // seconds between the touchplate firing and the action
 }
 
 func (r *RTLMapData) AddTrigger(srcActor *ActorInfo, X, Y int, action TriggerAction) {
 	trigger := MapTrigger{
 		Actor:  srcActor,
 		Action: action,
+		Delay:  r.TouchplateDelay(X, Y),
 	}
 	r.ActorGrid[Y][X].MapTriggers = append(r.ActorGrid[Y][X].MapTriggers, trigger)
 }
+
+// returns the touchplate that fires the actor's action (if any)
+func (r *RTLMapData) ActorTrigger(actor *ActorInfo, action TriggerAction) (*MapTrigger, int, int) {
+	touchplateX, touchplateY, ok := r.TouchplateLocation(actor.InfoValue)
+	if !ok {
+		return nil, 0, 0
+	}
+	for i, trigger := range r.ActorGrid[touchplateY][touchplateX].MapTriggers {
+		if trigger.Actor == actor && trigger.Action == action {
+			return &r.ActorGrid[touchplateY][touchplateX].MapTriggers[i], touchplateX, touchplateY
+		}
+	}
+	return nil, 0, 0
+}
+
+// returns true if the tile can be a touchplate: a switch, or a floor
+// tile the player can step on
+func (r *RTLMapData) IsTouchplate(x, y int) bool {
+	actor := &r.ActorGrid[y][x]
+	if actor.IsSwitch() {
+		return true
+	}
+	return !actor.IsWall() && r.IsFloor(x, y)
+}
+
+// info values pointing at a touchplate (or wall switch) are stored as
+// (x << 8) | y, anything not referencing one is something else
+// (heights, door timers, etc.)
+func (r *RTLMapData) TouchplateLocation(infoVal uint16) (int, int, bool) {
+	if infoVal == 0 || infoVal&0xff00 == 0xb000 {
+		return 0, 0, false
+	}
+	touchplateX := int((infoVal >> 8) & 0xff)
+	touchplateY := int(infoVal & 0xff)
+	if touchplateX < 1 || touchplateX > 126 || touchplateY < 1 || touchplateY > 126 {
+		return 0, 0, false
+	}
+	if !r.IsTouchplate(touchplateX, touchplateY) {
+		return 0, 0, false
+	}
+	return touchplateX, touchplateY, true
+}
+
+// Touchplates holding a timer in the info plane (minutes in the high
+// byte, seconds in the low byte) only fire their actions once it runs
+// out. Returns the delay in seconds, 0 if the touchplate has none.
+func (r *RTLMapData) TouchplateDelay(touchplateX, touchplateY int) float64 {
+	if touchplateX < 0 || touchplateX > 127 || touchplateY < 0 || touchplateY > 127 {
+		return 0
+	}
+	infoVal := r.ActorGrid[touchplateY][touchplateX].InfoValue
+	minutes := int(infoVal >> 8)
+	seconds := int(infoVal & 0xff)
+	// anything else is a height, an exit, a link to another
+	// touchplate, etc.
+	if infoVal == 0 || minutes >= 0x10 || seconds >= 60 {
+		return 0
+	}
+	if _, _, ok := r.TouchplateLocation(infoVal); ok {
+		return 0
+	}
+	return float64(minutes*60 + seconds)
+}
+
+// name of the trigger_relay entities fired by the touchplate
+func TouchplateTargetName(touchplateX, touchplateY int) string {
+	return fmt.Sprintf("trigger_%d_%d", touchplateX, touchplateY)
+}
+
+// spawns a trigger_relay that fires the target when the touchplate at
+// (touchplateX, touchplateY) is stepped on, delay seconds later
+func SpawnTriggerRelay(q *quakemap.QuakeMap, originX, originY, originZ float64,
+	touchplateX, touchplateY int, target string, delay float64) *quakemap.Entity {
+
+	relayEntity := q.SpawnEntity("trigger_relay", 0)
+	relayEntity.OriginX = originX
+	relayEntity.OriginY = originY
+	relayEntity.OriginZ = originZ
+	relayEntity.AdditionalKeys["targetname"] = TouchplateTargetName(touchplateX, touchplateY)
+	relayEntity.AdditionalKeys["target"] = target
+	if delay > 0 {
+		relayEntity.AdditionalKeys["delay"] = fmt.Sprintf("%.02f", delay)
+	}
+	return relayEntity
+}
+
+// registers touchplate actions for GADs, lights, obstacles and
+// enemies, walls and doors register their own
+func (r *RTLMapData) determineTriggers() {
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			actor := &r.ActorGrid[y][x]
+			touchplateX, touchplateY, ok := r.TouchplateLocation(actor.InfoValue)
+			if !ok {
+				continue
+			}
+			switch {
+			case actor.Type == SPR_GAD && actor.SpriteValue != StaticGAD:
+				r.AddTrigger(actor, touchplateX, touchplateY, TRIGGER_GAD)
+			case actor.SpriteValue == LightPost:
+				r.AddTrigger(actor, touchplateX, touchplateY, TRIGGER_LightToggle)
+			case IsObstacle(actor.SpriteValue):
+				r.AddTrigger(actor, touchplateX, touchplateY, TRIGGER_Obstacle)
+			case actor.Enemy != nil:
+				// dormant until the touchplate wakes it up
+				actor.Enemy.Ambush = true
+				r.AddTrigger(actor, touchplateX, touchplateY, TRIGGER_EnemyActivate)
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package rtl
+
+import (
+	"testing"
+)
+
+func TestTouchplateDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		infoVal uint16
+		delay   float64
+	}{
+		{"none", 0, 0},
+		{"seconds", 0x0005, 5},
+		{"minutes and seconds", 0x0102, 62},
+		{"height", 0xb001, 0},
+		{"exit", 0xe201, 0},
+		{"invalid seconds", 0x0040, 0},
+		// points at the touchplate at (3,4)
+		{"touchplate link", 0x0304, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var r RTLMapData
+			r.WallPlane[4][3] = AreaTileMin
+			r.ActorGrid[2][2].InfoValue = test.infoVal
+			if delay := r.TouchplateDelay(2, 2); delay != test.delay {
+				t.Errorf("expected a delay of %.0f, got %.0f", test.delay, delay)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package rtl
+
+// Doom maps (UDMF) for GZDoom. ROTT's grid maps onto Doom's sectors
+// without any brushes: every tile edge between a wall and open space is
+// a linedef, all open space is one sector, and every door and pushwall a
+// sector of its own.
+
+import (
+	"fmt"
+	"log"
+
+	"gitlab.com/camtap/rott2quake/pkg/udmf"
+)
+
+const (
+	UDMFNamespace  = "zdoom"
+	UDMFLightLevel = 192
+	UDMFSkyFlat    = "F_SKY1"
+
+	// ROTT and Doom both run at 35 tics per second
+	udmfTicsPerSecond = 35.0
+	// for tiles without a usable texture
+	udmfFallbackTexture = "WALL1"
+	// trigger lines sit this far inside their tile
+	udmfTriggerInset = 16.0
+)
+
+// Doom thing (DoomEdNum) and ZDoom lock number of each ROTT key
+var DoomKeys = map[DoorLock]struct{ Thing, Lock int }{
+	LOCK_GoldKey:   {6, 3},  // yellow keycard
+	LOCK_SilverKey: {5, 2},  // blue keycard
+	LOCK_IronKey:   {13, 1}, // red keycard
+	LOCK_OscuroKey: {38, 4}, // red skull key
+}
+
+// Quake entities by their closest Doom thing (DoomEdNum), items and
+// enemies are converted through their Quake entity
+var DoomThingTypes = map[string]int{
+	"monster_army":          3004, // zombieman
+	"monster_dog":           3002, // demon
+	"monster_enforcer":      9,    // shotgun guy
+	"monster_ogre":          65,   // chaingunner
+	"monster_ogre_marksman": 65,
+	"monster_knight":        3001, // imp
+	"monster_hell_knight":   69,   // hell knight
+	"monster_wizard":        3005, // cacodemon
+	"monster_shalrath":      66,   // revenant
+	"monster_shambler":      3003, // baron of hell
+	"monster_demon1":        3002,
+
+	"item_health":                   2012, // medikit
+	"item_armor2":                   2019, // blue armor
+	"item_artifact_invulnerability": 2022,
+	"item_artifact_super_damage":    2023, // berserk
+	"item_artifact_invisibility":    2024, // partial invisibility
+	"weapon_shotgun":                2001,
+	"weapon_supershotgun":           82,
+	"weapon_nailgun":                2002, // chaingun
+	"weapon_grenadelauncher":        2003, // rocket launcher
+	"weapon_rocketlauncher":         2003,
+	"weapon_lightning":              2004, // plasma rifle
+	"misc_explobox":                 2035, // barrel
+	"misc_explobox2":                2035,
+}
+
+// returns the name of the map's lump in a PWAD
+func (r *RTLMapData) DoomMapName() string {
+	return fmt.Sprintf("MAP%02d", r.MapNumber())
+}
+
+// returns true for tiles that are solid in Doom, anything else is open
+// space with at most a thin line through it
+func isUDMFSolid(actor *ActorInfo) bool {
+	switch actor.Type {
+	case WALL_Regular, WALL_Elevator, WALL_Switch, WALL_AnimatedWall:
+		return !actor.IsPushWall()
+	}
+	return false
+}
+
+// returns the corners of a tile's edge facing the direction, ordered so
+// the tile is on the front side of a line between them
+func udmfTileEdge(x, y int, direction WallDirection) (float64, float64, float64, float64) {
+	left, right := float64(x)*64.0, float64(x+1)*64.0
+	top, bottom := float64(y)*-64.0, float64(y+1)*-64.0
+	switch direction {
+	case DIR_North:
+		return left, top, right, top
+	case DIR_East:
+		return right, top, right, bottom
+	case DIR_South:
+		return right, bottom, left, bottom
+	default:
+		return left, bottom, left, top
+	}
+}
+
+// adds a square of lines inside the tile, crossing any of them
+// activates the special
+func addUDMFTrigger(m *udmf.Map, x, y, sector int, special int, args [5]int) {
+	if sector < 0 {
+		log.Printf("Trigger at (%d,%d) is inside a wall, skipping", x, y)
+		return
+	}
+	x1, y1 := float64(x)*64.0+udmfTriggerInset, float64(y)*-64.0-udmfTriggerInset
+	x2, y2 := float64(x+1)*64.0-udmfTriggerInset, float64(y+1)*-64.0+udmfTriggerInset
+	corners := [][2]float64{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}, {x1, y1}}
+	for i := 0; i < 4; i++ {
+		line := m.AddLine(corners[i][0], corners[i][1], corners[i+1][0], corners[i+1][1],
+			udmf.Sidedef{Sector: sector}, &udmf.Sidedef{Sector: sector})
+		line.Special = special
+		line.Args = args
+		line.PlayerCross = true
+		line.RepeatSpecial = true
+	}
+}
+
+// converts a speed in units per second to Doom's 1/8 units per tic
+func udmfSpeed(unitsPerSecond float64) int {
+	return int(unitsPerSecond / udmfTicsPerSecond * 8.0)
+}
+
+// returns the special and its arguments opening the door when used from
+// the front side of its lines, 0 if the door only opens by touchplate
+func udmfDoorSpecial(door *Door) (int, [5]int) {
+	behavior := door.Behavior()
+	speed := udmfSpeed(behavior.Speed)
+	delay := 0
+	if behavior.Wait >= 0 {
+		delay = int(behavior.Wait * udmfTicsPerSecond)
+	}
+	switch door.Lock {
+	case LOCK_Unlocked:
+		if delay == 0 {
+			return udmf.SPECIAL_DoorOpen, [5]int{0, speed}
+		}
+		return udmf.SPECIAL_DoorRaise, [5]int{0, speed, delay}
+	case LOCK_Trigger:
+		return 0, [5]int{}
+	default:
+		return udmf.SPECIAL_DoorLockedRaise, [5]int{0, speed, delay, DoomKeys[door.Lock].Lock}
+	}
+}
+
+func udmfAngle(direction WallDirection) int {
+	switch direction {
+	case DIR_North:
+		return 90
+	case DIR_West:
+		return 180
+	case DIR_South:
+		return 270
+	default:
+		return 0
+	}
+}
+
+func addUDMFThing(m *udmf.Map, x, y, thingType int, angle int, difficulty Difficulty, ambush bool) {
+	m.AddThing(udmf.Thing{
+		Type:        thingType,
+		X:           (float64(x) + 0.5) * 64.0,
+		Y:           (float64(y) + 0.5) * -64.0,
+		Angle:       angle,
+		SkillEasy:   difficulty != DifficultyHard,
+		SkillNormal: difficulty != DifficultyHard,
+		SkillHard:   true,
+		Ambush:      ambush,
+	})
+}
+
+func ConvertRTLMapToUDMF(rtlmap *RTLMapData) *udmf.Map {
+	m := udmf.NewMap(UDMFNamespace)
+
+	ceilingTexture := rtlmap.CeilingTexture()
+	if ceilingTexture == "" {
+		ceilingTexture = UDMFSkyFlat
+	}
+	openSector := udmf.Sector{
+		HeightCeiling:  rtlmap.FloorHeight() * 64,
+		TextureFloor:   rtlmap.FloorTexture(),
+		TextureCeiling: ceilingTexture,
+		LightLevel:     UDMFLightLevel,
+	}
+	// closed doors are sectors with their ceiling on the floor
+	closedSector := openSector
+	closedSector.HeightCeiling = 0
+
+	// sector of every tile, -1 for solid ones
+	var sectors [128][128]int
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if isUDMFSolid(&rtlmap.ActorGrid[y][x]) {
+				sectors[y][x] = -1
+			}
+		}
+	}
+	m.AddSector(openSector)
+
+	// the door of each door sector, pushwall sectors have none
+	doorSectors := make(map[int]*Door)
+	for i := range rtlmap.Doors {
+		door := &rtlmap.Doors[i]
+		sector := m.AddSector(closedSector)
+		m.Sectors[sector].ID = sector
+		doorSectors[sector] = door
+		for _, doorTile := range door.Tiles {
+			sectors[doorTile.Y][doorTile.X] = sector
+		}
+	}
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if rtlmap.ActorGrid[y][x].IsPushWall() {
+				sectors[y][x] = m.AddSector(closedSector)
+			}
+		}
+	}
+
+	// touchplate doors open when walking over the touchplate
+	for i := range rtlmap.Doors {
+		door := &rtlmap.Doors[i]
+		if door.Lock != LOCK_Trigger {
+			continue
+		}
+		doorSector := sectors[door.Tiles[0].Y][door.Tiles[0].X]
+		_, args := udmfDoorSpecial(door)
+		args[0] = doorSector
+		addUDMFTrigger(m, door.TriggerX, door.TriggerY, sectors[door.TriggerY][door.TriggerX], udmf.SPECIAL_DoorOpen, args)
+	}
+
+	// returns the texture of the wall at (x, y) as seen from the
+	// direction it faces
+	wallTexture := func(x, y int, direction WallDirection) string {
+		if x < 0 || x > 127 || y < 0 || y > 127 {
+			return udmfFallbackTexture
+		}
+		if jambTexture := rtlmap.JambTexture(x, y, direction); jambTexture != "" {
+			return jambTexture
+		}
+		if texture := rtlmap.ActorGrid[y][x].WallTileToTextureName(true); texture != "" {
+			return texture
+		}
+		return udmfFallbackTexture
+	}
+
+	opposite := map[WallDirection]WallDirection{
+		DIR_North: DIR_South, DIR_East: DIR_West, DIR_South: DIR_North, DIR_West: DIR_East,
+	}
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			sector := sectors[y][x]
+			if sector < 0 {
+				continue
+			}
+			actor := &rtlmap.ActorGrid[y][x]
+
+			for _, direction := range []WallDirection{DIR_North, DIR_East, DIR_South, DIR_West} {
+				deltaX, deltaY := direction.Delta()
+				neighborX, neighborY := x+deltaX, y+deltaY
+				x1, y1, x2, y2 := udmfTileEdge(x, y, direction)
+
+				neighborSector := -1
+				if neighborX >= 0 && neighborX <= 127 && neighborY >= 0 && neighborY <= 127 {
+					neighborSector = sectors[neighborY][neighborX]
+				}
+				if neighborSector < 0 {
+					m.AddLine(x1, y1, x2, y2, udmf.Sidedef{
+						Sector:        sector,
+						TextureMiddle: wallTexture(neighborX, neighborY, opposite[direction]),
+					}, nil)
+					continue
+				}
+				// two-sided lines face out of the lower sector
+				if neighborSector <= sector {
+					continue
+				}
+
+				front := udmf.Sidedef{Sector: sector}
+				back := udmf.Sidedef{Sector: neighborSector}
+				line := m.AddLine(x1, y1, x2, y2, front, &back)
+				if sector != 0 {
+					continue
+				}
+				// open space into a door or pushwall
+				if door, ok := doorSectors[neighborSector]; ok {
+					if texInfo := GetDoorTextures(door.Tiles[0].Tile); texInfo != nil {
+						m.Sidedefs[line.SideFront].TextureTop = texInfo.BaseTexture
+					}
+					line.Special, line.Args = udmfDoorSpecial(door)
+					line.MonsterUse = door.Lock == LOCK_Unlocked
+				} else {
+					m.Sidedefs[line.SideFront].TextureTop = wallTexture(neighborX, neighborY, opposite[direction])
+					line.Special, line.Args = udmf.SPECIAL_DoorOpen, [5]int{0, udmfSpeed(MovingObjectBaseSpeed)}
+					line.Secret = true
+				}
+				if line.Special != 0 {
+					line.PlayerUse = true
+					line.RepeatSpecial = true
+				}
+			}
+
+			// thin walls through the middle of the tile
+			switch actor.Type {
+			case WALL_MaskedWall, WALL_ThinWall, WALL_Window:
+				texture := actor.WallTileToTextureName(true)
+				if texture == "" {
+					break
+				}
+				var x1, y1, x2, y2 float64
+				if thinWallDirection, _, _ := rtlmap.ThinWallDirection(x, y); thinWallDirection == WALLDIR_NorthSouth {
+					x1, y1, x2, y2 = (float64(x)+0.5)*64.0, float64(y)*-64.0, (float64(x)+0.5)*64.0, float64(y+1)*-64.0
+				} else {
+					x1, y1, x2, y2 = float64(x)*64.0, (float64(y)+0.5)*-64.0, float64(x+1)*64.0, (float64(y)+0.5)*-64.0
+				}
+				side := udmf.Sidedef{Sector: sector, TextureMiddle: texture}
+				line := m.AddLine(x1, y1, x2, y2, side, &side)
+				line.Blocking = true
+				line.DontPegBottom = true
+				switch actor.Type {
+				case WALL_MaskedWall:
+					if maskedWallInfo, ok := MaskedWalls[actor.Tile]; ok && maskedWallInfo.Flags&MWF_BottomPassable != 0 {
+						line.Blocking = false
+					}
+				case WALL_Window:
+					line.Alpha = GlassAlpha
+				}
+			}
+		}
+	}
+
+	for _, point := range rtlmap.ExitPoints {
+		if rtlmap.rtl != nil && rtlmap.rtl.MapUsed(point.DestMap) {
+			addUDMFTrigger(m, point.X, point.Y, sectors[point.Y][point.X], udmf.SPECIAL_TeleportNewMap, [5]int{point.DestMap})
+		} else {
+			addUDMFTrigger(m, point.X, point.Y, sectors[point.Y][point.X], udmf.SPECIAL_ExitNormal, [5]int{})
+		}
+	}
+
+	var playerAngle int
+	switch rtlmap.SpawnDirection {
+	case 0: // up
+		playerAngle = 90
+	case 1: // right
+		playerAngle = 0
+	case 2: // down
+		playerAngle = 270
+	case 3: // left
+		playerAngle = 180
+	}
+	addUDMFThing(m, rtlmap.SpawnX, rtlmap.SpawnY, 1, playerAngle, DifficultyAll, false)
+
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			actor := &rtlmap.ActorGrid[y][x]
+
+			if lock := DoorLock(int(rtlmap.SpritePlane[y][x]) - 0x1c); actor.Type != WALL_Door && lock >= LOCK_GoldKey && lock <= LOCK_OscuroKey {
+				addUDMFThing(m, x, y, DoomKeys[lock].Thing, 0, DifficultyAll, false)
+			}
+
+			if item := actor.Item; item != nil {
+				name := item.EntityName(QuakeProfile{})
+				if thingType, ok := DoomThingTypes[name]; ok {
+					addUDMFThing(m, x, y, thingType, 0, DifficultyAll, false)
+				}
+			}
+
+			if enemy := actor.Enemy; enemy != nil {
+				choice := enemy.ConversionInfo.EntityChoice(actor, QuakeProfile{})
+				if choice == nil {
+					continue
+				}
+				thingType, ok := DoomThingTypes[choice.Name]
+				if !ok {
+					log.Printf("No Doom thing for %s at (%d,%d)", choice.Name, x, y)
+					continue
+				}
+				addUDMFThing(m, x, y, thingType, udmfAngle(enemy.Direction), enemy.Difficulty, enemy.Ambush)
+			}
+		}
+	}
+
+	log.Printf("UDMF map has %d linedefs, %d sectors and %d things", len(m.Linedefs), len(m.Sectors), len(m.Things))
+	return m
+}
@@ -0,0 +1,286 @@
+package udmf
+
+// Doom maps in the Universal Doom Map Format, as read by GZDoom from a
+// map's TEXTMAP lump
+
+import (
+	"fmt"
+	"strings"
+)
+
+// action specials of the ZDoom namespace (Hexen numbering)
+const (
+	SPECIAL_DoorOpen        = 11  // tag, speed, lighttag
+	SPECIAL_DoorRaise       = 12  // tag, speed, delay, lighttag
+	SPECIAL_DoorLockedRaise = 13  // tag, speed, delay, lock, lighttag
+	SPECIAL_TeleportNewMap  = 74  // map, position, face
+	SPECIAL_ExitNormal      = 243 // position
+	SPECIAL_ExitSecret      = 244 // position
+)
+
+type Vertex struct {
+	X, Y float64
+}
+
+type Linedef struct {
+	V1, V2    int
+	SideFront int
+	SideBack  int // -1 for one-sided lines
+	Special   int
+	Args      [5]int
+
+	Blocking      bool
+	TwoSided      bool
+	Secret        bool // drawn as a one-sided line on the automap
+	DontPegTop    bool
+	DontPegBottom bool
+	PlayerCross   bool
+	PlayerUse     bool
+	MonsterUse    bool
+	RepeatSpecial bool
+	Alpha         float64 // translucency of the middle texture, 0 is opaque
+}
+
+type Sidedef struct {
+	Sector        int
+	TextureTop    string
+	TextureBottom string
+	TextureMiddle string
+	OffsetX       int
+	OffsetY       int
+}
+
+type Sector struct {
+	HeightFloor    int
+	HeightCeiling  int
+	TextureFloor   string
+	TextureCeiling string
+	LightLevel     int
+	ID             int // tag
+}
+
+type Thing struct {
+	Type  int // DoomEdNum
+	X, Y  float64
+	Angle int
+
+	// skill levels 1 and 2, 3, and 4 and 5
+	SkillEasy   bool
+	SkillNormal bool
+	SkillHard   bool
+	Ambush      bool
+}
+
+type Map struct {
+	Namespace string
+	Vertices  []Vertex
+	Linedefs  []Linedef
+	Sidedefs  []Sidedef
+	Sectors   []Sector
+	Things    []Thing
+
+	vertexIndex map[Vertex]int
+}
+
+func NewMap(namespace string) *Map {
+	var m Map
+	m.Namespace = namespace
+	m.vertexIndex = make(map[Vertex]int)
+	return &m
+}
+
+// returns the index of the vertex at (x, y), adding it if needed
+func (m *Map) AddVertex(x, y float64) int {
+	vertex := Vertex{x, y}
+	if index, ok := m.vertexIndex[vertex]; ok {
+		return index
+	}
+	m.Vertices = append(m.Vertices, vertex)
+	m.vertexIndex[vertex] = len(m.Vertices) - 1
+	return len(m.Vertices) - 1
+}
+
+func (m *Map) AddSector(sector Sector) int {
+	m.Sectors = append(m.Sectors, sector)
+	return len(m.Sectors) - 1
+}
+
+func (m *Map) AddSidedef(side Sidedef) int {
+	m.Sidedefs = append(m.Sidedefs, side)
+	return len(m.Sidedefs) - 1
+}
+
+// adds a line from (x1, y1) to (x2, y2), the front side is on the right
+// when looking from the first point to the second. The returned line is
+// only valid until the next one is added.
+func (m *Map) AddLine(x1, y1, x2, y2 float64, front Sidedef, back *Sidedef) *Linedef {
+	line := Linedef{
+		V1:        m.AddVertex(x1, y1),
+		V2:        m.AddVertex(x2, y2),
+		SideFront: m.AddSidedef(front),
+		SideBack:  -1,
+	}
+	if back != nil {
+		line.SideBack = m.AddSidedef(*back)
+		line.TwoSided = true
+	}
+	m.Linedefs = append(m.Linedefs, line)
+	return &m.Linedefs[len(m.Linedefs)-1]
+}
+
+func (m *Map) AddThing(thing Thing) {
+	m.Things = append(m.Things, thing)
+}
+
+// returns every texture and flat name used on the map
+func (m *Map) Textures() []string {
+	seen := make(map[string]bool)
+	var textures []string
+	add := func(name string) {
+		if name != "" && name != "-" && !seen[name] {
+			seen[name] = true
+			textures = append(textures, name)
+		}
+	}
+	for _, side := range m.Sidedefs {
+		add(side.TextureTop)
+		add(side.TextureMiddle)
+		add(side.TextureBottom)
+	}
+	for _, sector := range m.Sectors {
+		add(sector.TextureFloor)
+		add(sector.TextureCeiling)
+	}
+	return textures
+}
+
+// collects the "key = value;" lines of a block
+type block struct {
+	kind  string
+	index int
+	lines []string
+}
+
+func (b *block) add(key string, format string, value interface{}) {
+	b.lines = append(b.lines, fmt.Sprintf("%s = "+format+";", key, value))
+}
+
+func (b *block) addBool(key string, value bool) {
+	if value {
+		b.lines = append(b.lines, fmt.Sprintf("%s = true;", key))
+	}
+}
+
+func (b *block) addInt(key string, value int) {
+	if value != 0 {
+		b.add(key, "%d", value)
+	}
+}
+
+func (b *block) addTexture(key string, value string) {
+	if value != "" && value != "-" {
+		b.add(key, "%q", value)
+	}
+}
+
+func (b *block) render() string {
+	return fmt.Sprintf("%s // %d\n{\n%s\n}\n", b.kind, b.index, strings.Join(b.lines, "\n"))
+}
+
+func (v *Vertex) render(index int) string {
+	b := block{kind: "vertex", index: index}
+	b.add("x", "%.03f", v.X)
+	b.add("y", "%.03f", v.Y)
+	return b.render()
+}
+
+func (l *Linedef) render(index int) string {
+	b := block{kind: "linedef", index: index}
+	b.add("v1", "%d", l.V1)
+	b.add("v2", "%d", l.V2)
+	b.add("sidefront", "%d", l.SideFront)
+	if l.SideBack >= 0 {
+		b.add("sideback", "%d", l.SideBack)
+	}
+	b.addInt("special", l.Special)
+	for i, arg := range l.Args {
+		b.addInt(fmt.Sprintf("arg%d", i), arg)
+	}
+	b.addBool("blocking", l.Blocking)
+	b.addBool("twosided", l.TwoSided)
+	b.addBool("secret", l.Secret)
+	b.addBool("dontpegtop", l.DontPegTop)
+	b.addBool("dontpegbottom", l.DontPegBottom)
+	b.addBool("playercross", l.PlayerCross)
+	b.addBool("playeruse", l.PlayerUse)
+	b.addBool("monsteruse", l.MonsterUse)
+	b.addBool("repeatspecial", l.RepeatSpecial)
+	if l.Alpha > 0 && l.Alpha < 1 {
+		b.add("alpha", "%.02f", l.Alpha)
+		b.add("renderstyle", "%q", "translucent")
+	}
+	return b.render()
+}
+
+func (s *Sidedef) render(index int) string {
+	b := block{kind: "sidedef", index: index}
+	b.add("sector", "%d", s.Sector)
+	b.addTexture("texturetop", s.TextureTop)
+	b.addTexture("texturebottom", s.TextureBottom)
+	b.addTexture("texturemiddle", s.TextureMiddle)
+	b.addInt("offsetx", s.OffsetX)
+	b.addInt("offsety", s.OffsetY)
+	return b.render()
+}
+
+func (s *Sector) render(index int) string {
+	b := block{kind: "sector", index: index}
+	b.add("heightfloor", "%d", s.HeightFloor)
+	b.add("heightceiling", "%d", s.HeightCeiling)
+	b.add("texturefloor", "%q", s.TextureFloor)
+	b.add("textureceiling", "%q", s.TextureCeiling)
+	b.add("lightlevel", "%d", s.LightLevel)
+	b.addInt("id", s.ID)
+	return b.render()
+}
+
+func (t *Thing) render(index int) string {
+	b := block{kind: "thing", index: index}
+	b.add("type", "%d", t.Type)
+	b.add("x", "%.03f", t.X)
+	b.add("y", "%.03f", t.Y)
+	b.add("angle", "%d", t.Angle)
+	b.addBool("skill1", t.SkillEasy)
+	b.addBool("skill2", t.SkillEasy)
+	b.addBool("skill3", t.SkillNormal)
+	b.addBool("skill4", t.SkillHard)
+	b.addBool("skill5", t.SkillHard)
+	b.addBool("ambush", t.Ambush)
+	b.addBool("single", true)
+	b.addBool("coop", true)
+	b.addBool("dm", true)
+	return b.render()
+}
+
+// returns the contents of the TEXTMAP lump
+func (m *Map) Render() string {
+	// maps easily run into tens of thousands of blocks
+	var output strings.Builder
+	fmt.Fprintf(&output, "namespace = %q;\n\n", m.Namespace)
+	for i, thing := range m.Things {
+		output.WriteString(thing.render(i) + "\n")
+	}
+	for i, vertex := range m.Vertices {
+		output.WriteString(vertex.render(i) + "\n")
+	}
+	for i, line := range m.Linedefs {
+		output.WriteString(line.render(i) + "\n")
+	}
+	for i, side := range m.Sidedefs {
+		output.WriteString(side.render(i) + "\n")
+	}
+	for i, sector := range m.Sectors {
+		output.WriteString(sector.render(i) + "\n")
+	}
+	return output.String()
+}
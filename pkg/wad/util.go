@@ -39,7 +39,12 @@ func GetImageFromFlatData(lumpReader io.Reader, iwad lumps.ArchiveReader, width
 
 	for i := 0; i < height; i++ {
 		for j := 0; j < width; j++ {
-			r, g, b, _ := pal[rawImgData[(i*width)+j]].RGBA()
+			paletteIndex := rawImgData[(i*width)+j]
+			if transparent && paletteIndex == 0xff {
+				// leave see-through pixels alone
+				continue
+			}
+			r, g, b, _ := pal[paletteIndex].RGBA()
 			img.SetRGBA(i, j, color.RGBA{uint8(r), uint8(g), uint8(b), 0xff})
 		}
 	}
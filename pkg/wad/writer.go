@@ -0,0 +1,74 @@
+package wad
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// lump of a PWAD being written
+type Lump struct {
+	Name string
+	Data []byte
+}
+
+type PWADWriter struct {
+	Directory []Lump
+}
+
+func NewPWADWriter() (*PWADWriter, error) {
+	var writer PWADWriter
+
+	return &writer, nil
+}
+
+// adds a lump, markers like MAP01 or TX_START have no data
+func (w *PWADWriter) AddLump(name string, data []byte) error {
+	if len(name) > 8 {
+		return fmt.Errorf("lump name %s longer than 8 chars", name)
+	}
+	w.Directory = append(w.Directory, Lump{Name: name, Data: data})
+
+	return nil
+}
+
+func (w *PWADWriter) Write(dest io.WriteSeeker) (int64, error) {
+	var header WADHeader
+
+	copy(header.Magic[:], pwadMagic[:])
+	header.NumLumps = uint32(len(w.Directory))
+	header.DirectoryOffset = uint32(binary.Size(header))
+
+	if err := binary.Write(dest, binary.LittleEndian, &header); err != nil {
+		return 0, err
+	}
+
+	entrysize := binary.Size(LumpHeader{})
+
+	// total written
+	total := int64(header.DirectoryOffset)
+	// calculated position of lump data in file
+	offset := header.DirectoryOffset + uint32(entrysize*len(w.Directory))
+
+	for _, lump := range w.Directory {
+		// names shorter than 8 chars are padded with null bytes
+		direntry := LumpHeader{
+			FilePos: offset,
+			Size:    uint32(len(lump.Data)),
+		}
+		copy(direntry.Name[:], []byte(lump.Name))
+		if err := binary.Write(dest, binary.LittleEndian, &direntry); err != nil {
+			return total, err
+		}
+		offset += uint32(len(lump.Data))
+		total += int64(entrysize)
+	}
+	for _, lump := range w.Directory {
+		if _, err := dest.Write(lump.Data); err != nil {
+			return total, err
+		}
+		total += int64(len(lump.Data))
+	}
+
+	return total, nil
+}
@@ -0,0 +1,100 @@
+package wal
+
+// Quake 2 .wal textures, one file per texture with its own MIP levels
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+
+	"gitlab.com/camtap/rott2quake/pkg/wad2"
+)
+
+const (
+	// both palettes keep their last color for transparency
+	TransparentIndex = 255
+
+	mipLevels = 4
+)
+
+type WALHeader struct {
+	Name     [32]byte
+	Width    uint32
+	Height   uint32
+	Offsets  [mipLevels]uint32
+	AnimName [32]byte // next frame of an animated texture
+	Flags    int32    // default surface flags
+	Contents int32    // default contents
+	Value    int32    // default light value
+}
+
+// returns a table translating the indices of one palette to the
+// closest colors of another, leaving out the transparent color
+func PaletteTranslation(srcPalette, destPalette color.Palette) [256]uint8 {
+	var table [256]uint8
+	if len(destPalette) > TransparentIndex {
+		destPalette = destPalette[:TransparentIndex]
+	}
+	for i := range table {
+		if i == TransparentIndex || i >= len(srcPalette) {
+			table[i] = uint8(i)
+			continue
+		}
+		table[i] = uint8(destPalette.Index(srcPalette[i]))
+	}
+	return table
+}
+
+func walName(name string) ([32]byte, error) {
+	var processedName [32]byte
+	if len(name) > 31 {
+		return processedName, fmt.Errorf("texture name %s longer than 31 chars", name)
+	}
+	copy(processedName[:], []byte(name))
+	return processedName, nil
+}
+
+// converts a Quake MIP texture (as stored in wad2 files) into .wal data,
+// translating its pixels with a table from PaletteTranslation. name and
+// animName are paths relative to the textures/ folder, without extension.
+func MIPTextureToWAL(mipData []byte, name, animName string, translation *[256]uint8) ([]byte, error) {
+	var mip wad2.MIPTexture
+	if err := binary.Read(bytes.NewReader(mipData), binary.LittleEndian, &mip); err != nil {
+		return nil, fmt.Errorf("could not read MIP texture header: %v", err)
+	}
+
+	var header WALHeader
+	var err error
+	if header.Name, err = walName(name); err != nil {
+		return nil, err
+	}
+	if header.AnimName, err = walName(animName); err != nil {
+		return nil, err
+	}
+	header.Width = uint32(mip.Width)
+	header.Height = uint32(mip.Height)
+
+	offset := uint32(binary.Size(header))
+	mipPos := []int32{mip.Scale1Pos, mip.Scale2Pos, mip.Scale4Pos, mip.Scale8Pos}
+	var pixels bytes.Buffer
+	for level := 0; level < mipLevels; level++ {
+		size := int32((mip.Width >> uint(level)) * (mip.Height >> uint(level)))
+		start := mipPos[level]
+		if start < 0 || start+size > int32(len(mipData)) {
+			return nil, fmt.Errorf("MIP level %d of %s out of bounds", level, name)
+		}
+		header.Offsets[level] = offset
+		for _, index := range mipData[start : start+size] {
+			pixels.WriteByte(translation[index])
+		}
+		offset += uint32(size)
+	}
+
+	b := new(bytes.Buffer)
+	if err := binary.Write(b, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	b.Write(pixels.Bytes())
+	return b.Bytes(), nil
+}